@@ -1,3 +1,5 @@
 module github.com/mwildt/go-http
 
 go 1.21
+
+require golang.org/x/crypto v0.32.0
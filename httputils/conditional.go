@@ -0,0 +1,109 @@
+package httputils
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func StrongETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+func WeakETag(data []byte) string {
+	return "W/" + StrongETag(data)
+}
+
+// CheckPreconditions evaluates the conditional request headers against the current etag and
+// lastModified of the resource (either may be empty or zero) as described in RFC 9110 section 13.2.2.
+// It sets the ETag and Last-Modified headers and returns true if a 304 or 412 response was sent.
+func CheckPreconditions(w http.ResponseWriter, request *http.Request, etag string, lastModified time.Time) bool {
+	header := w.Header()
+	if etag != "" {
+		header.Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if ifMatch := request.Header.Get("If-Match"); ifMatch != "" {
+		if !matchETag(ifMatch, etag, false) {
+			Send(w, request, http.StatusPreconditionFailed)
+			return true
+		}
+	} else if since, err := http.ParseTime(request.Header.Get("If-Unmodified-Since")); err == nil && !lastModified.IsZero() {
+		if lastModified.Truncate(time.Second).After(since) {
+			Send(w, request, http.StatusPreconditionFailed)
+			return true
+		}
+	}
+
+	safe := request.Method == http.MethodGet || request.Method == http.MethodHead
+	if ifNoneMatch := request.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if !matchETag(ifNoneMatch, etag, true) {
+			return false
+		} else if safe {
+			notModified(w)
+		} else {
+			Send(w, request, http.StatusPreconditionFailed)
+		}
+		return true
+	} else if since, err := http.ParseTime(request.Header.Get("If-Modified-Since")); err == nil && safe && !lastModified.IsZero() {
+		if !lastModified.Truncate(time.Second).After(since) {
+			notModified(w)
+			return true
+		}
+	}
+	return false
+}
+
+func notModified(w http.ResponseWriter) {
+	header := w.Header()
+	for _, name := range []string{"Content-Type", "Content-Length", "Content-Encoding", "Transfer-Encoding"} {
+		header.Del(name)
+	}
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// matchETag checks an If-Match or If-None-Match list, If-None-Match uses the weak comparison
+func matchETag(list string, etag string, weak bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return etag != ""
+	}
+	if etag == "" || (!weak && strings.HasPrefix(etag, "W/")) {
+		return false
+	}
+	for list != "" {
+		list = strings.TrimLeft(list, " \t,")
+		candidate, rest, ok := scanETag(list)
+		if !ok {
+			return false
+		}
+		list = rest
+		if weak && strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		} else if !weak && candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func scanETag(list string) (string, string, bool) {
+	start := 0
+	if strings.HasPrefix(list, "W/") {
+		start = 2
+	}
+	if len(list) <= start || list[start] != '"' {
+		return "", "", false
+	}
+	end := strings.IndexByte(list[start+1:], '"')
+	if end < 0 {
+		return "", "", false
+	}
+	end += start + 2
+	return list[:end], list[end:], true
+}
@@ -0,0 +1,43 @@
+package httputils
+
+import (
+	go_http "github.com/mwildt/go-http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckPreconditions(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		method string
+		header string
+		value  string
+		etag   string
+		status int
+	}{
+		{"GET", "", "", `"a"`, 0},
+		{"GET", "If-None-Match", `"a"`, `"a"`, 304},
+		{"GET", "If-None-Match", `W/"a"`, `"a"`, 304},
+		{"GET", "If-None-Match", `"b"`, `"a"`, 0},
+		{"GET", "If-None-Match", "*", "", 0},
+		{"PUT", "If-None-Match", "*", `"a"`, 412},
+		{"PUT", "If-Match", `"a", "b"`, `"b"`, 0},
+		{"PUT", "If-Match", `"a"`, `"b"`, 412},
+		{"PUT", "If-Match", "*", "", 412},
+		{"PUT", "If-Unmodified-Since", "Wed, 01 May 2024 11:00:00 GMT", `"a"`, 412},
+		{"PUT", "If-Unmodified-Since", "Wed, 01 May 2024 12:00:00 GMT", `"a"`, 0},
+		{"GET", "If-Modified-Since", "Wed, 01 May 2024 12:00:00 GMT", `"a"`, 304},
+		{"GET", "If-Modified-Since", "Wed, 01 May 2024 11:00:00 GMT", `"a"`, 0},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, "http://example.com/", nil)
+		if c.header != "" {
+			req.Header.Set(c.header, c.value)
+		}
+		recorder := httptest.NewRecorder()
+		done := CheckPreconditions(recorder, req, c.etag, modified)
+		go_http.Assert(t, done == (c.status != 0), "%s %s: %s unexpected result %v", c.method, c.header, c.value, done)
+		go_http.Assert(t, c.status == 0 || recorder.Code == c.status, "%s %s: %s expected %d, got %d", c.method, c.header, c.value, c.status, recorder.Code)
+	}
+}
@@ -0,0 +1,183 @@
+package httputils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCookieInvalid = errors.New("invalid cookie")
+	ErrCookieExpired = errors.New("cookie expired")
+)
+
+// CookieOptions defaults to secure cookies: HttpOnly, Secure and SameSite=Lax
+type CookieOptions struct {
+	Path   string
+	Domain string
+	// MaxAge is also part of the signed or encrypted value, so expired cookies are rejected even if sent
+	MaxAge   time.Duration
+	SameSite http.SameSite
+	// Insecure allows the cookie over plain http, ScriptAccess removes HttpOnly
+	Insecure     bool
+	ScriptAccess bool
+}
+
+func (options CookieOptions) cookie(name string, value string) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     options.Path,
+		Domain:   options.Domain,
+		Secure:   !options.Insecure,
+		HttpOnly: !options.ScriptAccess,
+		SameSite: options.SameSite,
+	}
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	if cookie.SameSite == 0 {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	if options.MaxAge > 0 {
+		cookie.MaxAge = int(options.MaxAge.Seconds())
+	}
+	return cookie
+}
+
+func (options CookieOptions) expires(now time.Time) int64 {
+	if options.MaxAge > 0 {
+		return now.Add(options.MaxAge).Unix()
+	}
+	return 0
+}
+
+func DeleteCookie(w http.ResponseWriter, name string, options CookieOptions) {
+	cookie := options.cookie(name, "")
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
+}
+
+// SetSignedCookie signs the value with the first key, the value itself is readable by the client
+func SetSignedCookie(w http.ResponseWriter, name string, value string, keys [][]byte, options CookieOptions) error {
+	if len(keys) == 0 {
+		return errors.New("no cookie key")
+	}
+	payload := base64.RawURLEncoding.EncodeToString([]byte(value)) + "." + strconv.FormatInt(options.expires(time.Now()), 10)
+	signature := base64.RawURLEncoding.EncodeToString(cookieSignature(keys[0], name, payload))
+	http.SetCookie(w, options.cookie(name, payload+"."+signature))
+	return nil
+}
+
+// GetSignedCookie accepts signatures of all keys, so keys can be rotated
+func GetSignedCookie(request *http.Request, name string, keys [][]byte) (string, error) {
+	cookie, err := request.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(cookie.Value, ".")
+	if len(parts) != 3 {
+		return "", ErrCookieInvalid
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrCookieInvalid
+	}
+	payload := parts[0] + "." + parts[1]
+	for _, key := range keys {
+		if hmac.Equal(signature, cookieSignature(key, name, payload)) {
+			return decodeCookiePayload(parts[0], parts[1])
+		}
+	}
+	return "", ErrCookieInvalid
+}
+
+func cookieSignature(key []byte, name string, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + "=" + payload))
+	return mac.Sum(nil)
+}
+
+func decodeCookiePayload(encodedValue string, encodedExpires string) (string, error) {
+	expires, err := strconv.ParseInt(encodedExpires, 10, 64)
+	if err != nil {
+		return "", ErrCookieInvalid
+	} else if expires != 0 && time.Now().Unix() > expires {
+		return "", ErrCookieExpired
+	}
+	value, err := base64.RawURLEncoding.DecodeString(encodedValue)
+	if err != nil {
+		return "", ErrCookieInvalid
+	}
+	return string(value), nil
+}
+
+// SetEncryptedCookie encrypts the value with AES-GCM using the first key, keys must have 16, 24 or 32 bytes
+func SetEncryptedCookie(w http.ResponseWriter, name string, value string, keys [][]byte, options CookieOptions) error {
+	if len(keys) == 0 {
+		return errors.New("no cookie key")
+	}
+	aead, err := newCookieAEAD(keys[0])
+	if err != nil {
+		return err
+	}
+	plaintext := binary.BigEndian.AppendUint64(nil, uint64(options.expires(time.Now())))
+	plaintext = append(plaintext, value...)
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(name))
+	http.SetCookie(w, options.cookie(name, base64.RawURLEncoding.EncodeToString(sealed)))
+	return nil
+}
+
+// GetEncryptedCookie tries to decrypt with all keys, so keys can be rotated
+func GetEncryptedCookie(request *http.Request, name string, keys [][]byte) (string, error) {
+	cookie, err := request.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return "", ErrCookieInvalid
+	}
+	for _, key := range keys {
+		aead, err := newCookieAEAD(key)
+		if err != nil {
+			return "", err
+		}
+		if len(sealed) < aead.NonceSize() {
+			return "", ErrCookieInvalid
+		}
+		plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(name))
+		if err != nil {
+			continue
+		} else if len(plaintext) < 8 {
+			return "", ErrCookieInvalid
+		}
+		expires := int64(binary.BigEndian.Uint64(plaintext[:8]))
+		if expires != 0 && time.Now().Unix() > expires {
+			return "", ErrCookieExpired
+		}
+		return string(plaintext[8:]), nil
+	}
+	return "", ErrCookieInvalid
+}
+
+func newCookieAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
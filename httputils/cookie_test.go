@@ -0,0 +1,79 @@
+package httputils
+
+import (
+	"encoding/base64"
+	go_http "github.com/mwildt/go-http"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	cookieKey1 = []byte("0123456789abcdef0123456789abcdef")
+	cookieKey2 = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func replayCookie(recorder *httptest.ResponseRecorder) *http.Request {
+	req := httptest.NewRequest("GET", "http://example.com/", nil)
+	for _, cookie := range recorder.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	return req
+}
+
+func TestSignedCookie(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	SetSignedCookie(recorder, "user", "alice", [][]byte{cookieKey1}, CookieOptions{MaxAge: time.Hour})
+	cookie := recorder.Result().Cookies()[0]
+	go_http.Assert(t, cookie.HttpOnly && cookie.Secure && cookie.SameSite == http.SameSiteLaxMode && cookie.Path == "/", "secure defaults not applied %v", cookie)
+	go_http.Assert(t, cookie.MaxAge == 3600, "unexpected max-age %d", cookie.MaxAge)
+
+	value, err := GetSignedCookie(replayCookie(recorder), "user", [][]byte{cookieKey2, cookieKey1})
+	go_http.Assert(t, err == nil && value == "alice", "unexpected value %q %v", value, err)
+	_, err = GetSignedCookie(replayCookie(recorder), "user", [][]byte{cookieKey2})
+	go_http.Assert(t, err == ErrCookieInvalid, "unknown key accepted")
+
+	req := httptest.NewRequest("GET", "http://example.com/", nil)
+	req.AddCookie(&http.Cookie{Name: "user", Value: strings.Replace(cookie.Value, "YWxpY2U", "Ym9i", 1)})
+	_, err = GetSignedCookie(req, "user", [][]byte{cookieKey1})
+	go_http.Assert(t, err == ErrCookieInvalid, "tampered value accepted")
+	_, err = GetSignedCookie(req, "other", [][]byte{cookieKey1})
+	go_http.Assert(t, err == http.ErrNoCookie, "expected missing cookie, got %v", err)
+}
+
+func TestEncryptedCookie(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	err := SetEncryptedCookie(recorder, "secret", "alice", [][]byte{cookieKey1}, CookieOptions{Insecure: true, ScriptAccess: true})
+	go_http.Assert(t, err == nil, "unexpected error %v", err)
+	cookie := recorder.Result().Cookies()[0]
+	go_http.Assert(t, !cookie.HttpOnly && !cookie.Secure, "options not applied")
+	go_http.Assert(t, !strings.Contains(cookie.Value, "alice"), "value not encrypted")
+
+	value, err := GetEncryptedCookie(replayCookie(recorder), "secret", [][]byte{cookieKey2, cookieKey1})
+	go_http.Assert(t, err == nil && value == "alice", "unexpected value %q %v", value, err)
+	_, err = GetEncryptedCookie(replayCookie(recorder), "secret", [][]byte{cookieKey2})
+	go_http.Assert(t, err == ErrCookieInvalid, "unknown key accepted")
+
+	req := httptest.NewRequest("GET", "http://example.com/", nil)
+	req.AddCookie(&http.Cookie{Name: "renamed", Value: cookie.Value})
+	_, err = GetEncryptedCookie(req, "renamed", [][]byte{cookieKey1})
+	go_http.Assert(t, err == ErrCookieInvalid, "value accepted under another name")
+
+	err = SetEncryptedCookie(httptest.NewRecorder(), "secret", "alice", [][]byte{[]byte("short")}, CookieOptions{})
+	go_http.Assert(t, err != nil, "invalid key accepted")
+}
+
+func TestCookieExpiry(t *testing.T) {
+	payload := "dg.1"
+	signature := base64.RawURLEncoding.EncodeToString(cookieSignature(cookieKey1, "c", payload))
+	req := httptest.NewRequest("GET", "http://example.com/", nil)
+	req.AddCookie(&http.Cookie{Name: "c", Value: payload + "." + signature})
+	_, err := GetSignedCookie(req, "c", [][]byte{cookieKey1})
+	go_http.Assert(t, err == ErrCookieExpired, "expired signed cookie accepted %v", err)
+
+	recorder := httptest.NewRecorder()
+	DeleteCookie(recorder, "c", CookieOptions{})
+	go_http.Assert(t, recorder.Result().Cookies()[0].MaxAge == -1, "cookie not deleted")
+}
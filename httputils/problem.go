@@ -0,0 +1,33 @@
+package httputils
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Problem is a problem details object as defined by RFC 9457
+type Problem struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+func NewProblem(status int, detail string) Problem {
+	return Problem{Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+func SendProblem(w http.ResponseWriter, request *http.Request, problem Problem) {
+	if problem.Status == 0 {
+		problem.Status = http.StatusInternalServerError
+	}
+	payload, err := json.Marshal(problem)
+	if err != nil {
+		InternalServerError(w, request)
+	} else {
+		w.Header().Set("Content-Type", "application/problem+json")
+		Send(w, request, problem.Status)
+		w.Write(payload)
+	}
+}
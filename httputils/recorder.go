@@ -0,0 +1,50 @@
+package httputils
+
+import "net/http"
+
+type StatusRecorder struct {
+	http.ResponseWriter
+	Status int
+	Size   int64
+}
+
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	return &StatusRecorder{ResponseWriter: w}
+}
+
+func (recorder *StatusRecorder) WriteHeader(code int) {
+	if recorder.Status == 0 {
+		recorder.Status = code
+	}
+	recorder.ResponseWriter.WriteHeader(code)
+}
+
+func (recorder *StatusRecorder) Write(data []byte) (int, error) {
+	if recorder.Status == 0 {
+		recorder.Status = http.StatusOK
+	}
+	n, err := recorder.ResponseWriter.Write(data)
+	recorder.Size += int64(n)
+	return n, err
+}
+
+func (recorder *StatusRecorder) Flush() {
+	if recorder.Status == 0 {
+		recorder.Status = http.StatusOK
+	}
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (recorder *StatusRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
+
+// StatusCode returns the recorded status, a handler that never wrote anything results in 200
+func (recorder *StatusRecorder) StatusCode() int {
+	if recorder.Status == 0 {
+		return http.StatusOK
+	}
+	return recorder.Status
+}
@@ -14,6 +14,10 @@ func Unauthorized(w http.ResponseWriter, request *http.Request) {
 	Send(w, request, http.StatusUnauthorized)
 }
 
+func Forbidden(w http.ResponseWriter, request *http.Request) {
+	Send(w, request, http.StatusForbidden)
+}
+
 func NotFound(w http.ResponseWriter, request *http.Request) {
 	Send(w, request, http.StatusNotFound)
 }
@@ -43,3 +47,9 @@ func OkJson(w http.ResponseWriter, request *http.Request, data interface{}) {
 func CreatedJson(w http.ResponseWriter, request *http.Request, data interface{}) {
 	SendJson(w, request, http.StatusCreated, data)
 }
+
+func SendText(w http.ResponseWriter, request *http.Request, code int, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	Send(w, request, code)
+	w.Write([]byte(text))
+}
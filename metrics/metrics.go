@@ -0,0 +1,179 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mwildt/go-http/httputils"
+	"github.com/mwildt/go-http/routing"
+)
+
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestLabels struct {
+	method string
+	route  string
+	status string
+}
+
+type inFlightLabels struct {
+	method string
+	route  string
+}
+
+type series struct {
+	count   uint64
+	sum     float64
+	buckets []uint64
+}
+
+type Metrics struct {
+	buckets  []float64
+	mutex    sync.Mutex
+	requests map[requestLabels]*series
+	inFlight map[inFlightLabels]int64
+}
+
+func New(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	return &Metrics{
+		buckets:  buckets,
+		requests: make(map[requestLabels]*series),
+		inFlight: make(map[inFlightLabels]int64),
+	}
+}
+
+func (m *Metrics) Filter(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	route := routing.GetRoutePattern(r.Context())
+	flightLabels := inFlightLabels{method: r.Method, route: route}
+	m.addInFlight(flightLabels, 1)
+	defer m.addInFlight(flightLabels, -1)
+
+	start := time.Now()
+	recorder := httputils.NewStatusRecorder(w)
+	next(recorder, r)
+	m.observe(requestLabels{method: r.Method, route: route, status: statusClass(recorder.StatusCode())}, time.Since(start))
+}
+
+func (m *Metrics) addInFlight(labels inFlightLabels, delta int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.inFlight[labels] += delta
+}
+
+func (m *Metrics) observe(labels requestLabels, duration time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	s, exists := m.requests[labels]
+	if !exists {
+		s = &series{buckets: make([]uint64, len(m.buckets))}
+		m.requests[labels] = s
+	}
+	seconds := duration.Seconds()
+	s.count++
+	s.sum += seconds
+	for i, upperBound := range m.buckets {
+		if seconds <= upperBound {
+			s.buckets[i]++
+		}
+	}
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	httputils.Send(w, r, http.StatusOK)
+	m.WriteTo(w)
+}
+
+func (m *Metrics) WriteTo(writer io.Writer) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var out strings.Builder
+	keys := make([]requestLabels, 0, len(m.requests))
+	for labels := range m.requests {
+		keys = append(keys, labels)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		} else if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].status < keys[j].status
+	})
+
+	out.WriteString("# HELP http_requests_total Total number of HTTP requests.\n")
+	out.WriteString("# TYPE http_requests_total counter\n")
+	for _, labels := range keys {
+		fmt.Fprintf(&out, "http_requests_total%s %d\n", labels.format(), m.requests[labels].count)
+	}
+
+	out.WriteString("# HELP http_request_duration_seconds Duration of HTTP requests in seconds.\n")
+	out.WriteString("# TYPE http_request_duration_seconds histogram\n")
+	for _, labels := range keys {
+		s := m.requests[labels]
+		for i, upperBound := range m.buckets {
+			fmt.Fprintf(&out, "http_request_duration_seconds_bucket%s %d\n", labels.format("le", formatFloat(upperBound)), s.buckets[i])
+		}
+		fmt.Fprintf(&out, "http_request_duration_seconds_bucket%s %d\n", labels.format("le", "+Inf"), s.count)
+		fmt.Fprintf(&out, "http_request_duration_seconds_sum%s %s\n", labels.format(), formatFloat(s.sum))
+		fmt.Fprintf(&out, "http_request_duration_seconds_count%s %d\n", labels.format(), s.count)
+	}
+
+	flightKeys := make([]inFlightLabels, 0, len(m.inFlight))
+	for labels := range m.inFlight {
+		flightKeys = append(flightKeys, labels)
+	}
+	sort.Slice(flightKeys, func(i, j int) bool {
+		if flightKeys[i].route != flightKeys[j].route {
+			return flightKeys[i].route < flightKeys[j].route
+		}
+		return flightKeys[i].method < flightKeys[j].method
+	})
+
+	out.WriteString("# HELP http_requests_in_flight Number of HTTP requests currently being served.\n")
+	out.WriteString("# TYPE http_requests_in_flight gauge\n")
+	for _, labels := range flightKeys {
+		fmt.Fprintf(&out, "http_requests_in_flight%s %d\n", formatLabels("method", labels.method, "route", labels.route), m.inFlight[labels])
+	}
+
+	n, err := io.WriteString(writer, out.String())
+	return int64(n), err
+}
+
+func (labels requestLabels) format(extra ...string) string {
+	return formatLabels(append([]string{"method", labels.method, "route", labels.route, "status", labels.status}, extra...)...)
+}
+
+func formatLabels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+"=\""+escapeLabelValue(pairs[i+1])+"\"")
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var labelValueEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func statusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
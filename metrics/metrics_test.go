@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	go_http "github.com/mwildt/go-http"
+	"github.com/mwildt/go-http/routing"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	metrics := New(0.1, 1)
+	router := routing.NewRouter(func(router routing.Routing) {
+		router.Handle(routing.Get("/metrics"), metrics)
+		router.Route(routing.Filtering(metrics.Filter), func(router routing.Routing) {
+			router.HandleFunc(routing.Get("/api/{id}"), func(writer http.ResponseWriter, request *http.Request) {
+				writer.Write([]byte("OK"))
+			})
+			router.HandleFunc(routing.Post("/api/{id}"), func(writer http.ResponseWriter, request *http.Request) {
+				writer.WriteHeader(http.StatusBadRequest)
+			})
+		})
+	})
+
+	for _, method := range []string{"GET", "GET", "POST"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "http://example.com/api/1", nil))
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "http://example.com/metrics", nil))
+	body := recorder.Body.String()
+
+	go_http.Assert(t, recorder.Code == 200, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4"), "unexpected content type")
+	for _, expected := range []string{
+		`http_requests_total{method="GET",route="/api/{id}",status="2xx"} 2`,
+		`http_requests_total{method="POST",route="/api/{id}",status="4xx"} 1`,
+		`http_request_duration_seconds_bucket{method="GET",route="/api/{id}",status="2xx",le="+Inf"} 2`,
+		`http_request_duration_seconds_count{method="POST",route="/api/{id}",status="4xx"} 1`,
+		`http_requests_in_flight{method="GET",route="/api/{id}"} 0`,
+	} {
+		go_http.Assert(t, strings.Contains(body, expected+"\n"), "missing line '%s' in\n%s", expected, body)
+	}
+}
+
+func TestMetricsInFlight(t *testing.T) {
+	metrics := New()
+	var exposition string
+	router := routing.NewRouter(func(router routing.Routing) {
+		router.HandleFunc(routing.Get("/slow").Filter(metrics.Filter), func(writer http.ResponseWriter, request *http.Request) {
+			var out strings.Builder
+			metrics.WriteTo(&out)
+			exposition = out.String()
+		})
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://example.com/slow", nil))
+
+	go_http.Assert(t, strings.Contains(exposition, `http_requests_in_flight{method="GET",route="/slow"} 1`), "unexpected exposition %s", exposition)
+}
+
+func TestLabelEscaping(t *testing.T) {
+	labels := formatLabels("route", "a\"b\\c\nd")
+	go_http.Assert(t, labels == `{route="a\"b\\c\nd"}`, "unexpected labels %s", labels)
+}
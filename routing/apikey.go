@@ -0,0 +1,149 @@
+package routing
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mwildt/go-http/httputils"
+)
+
+const DefaultAPIKeyHeader = "X-API-Key"
+
+type APIKey struct {
+	Name   string
+	Scopes []string
+}
+
+type KeyStore interface {
+	Lookup(key string) (APIKey, bool)
+}
+
+func HashAPIKey(key string) string {
+	digest := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(digest[:])
+}
+
+// MemoryKeyStore only keeps the hashes of the given keys
+type MemoryKeyStore struct {
+	keys map[string]APIKey
+}
+
+func NewMemoryKeyStore(keys map[string]APIKey) *MemoryKeyStore {
+	store := &MemoryKeyStore{keys: make(map[string]APIKey, len(keys))}
+	for key, apiKey := range keys {
+		store.keys[HashAPIKey(key)] = apiKey
+	}
+	return store
+}
+
+func (store *MemoryKeyStore) Lookup(key string) (APIKey, bool) {
+	apiKey, exists := store.keys[HashAPIKey(key)]
+	return apiKey, exists
+}
+
+// HashedFileKeyStore reads lines of the form "<sha256 hex of key> <name> [scope,scope]", "#" starts a comment
+type HashedFileKeyStore struct {
+	path  string
+	mutex sync.RWMutex
+	keys  map[string]APIKey
+}
+
+func LoadHashedFileKeyStore(path string) (*HashedFileKeyStore, error) {
+	store := &HashedFileKeyStore{path: path}
+	if err := store.Reload(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (store *HashedFileKeyStore) Reload() error {
+	file, err := os.Open(store.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	keys := make(map[string]APIKey)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		} else if len(fields) > 3 || len(fields) < 2 {
+			return fmt.Errorf("%s:%d: expected '<hash> <name> [scopes]'", store.path, lineNumber)
+		} else if hash, err := hex.DecodeString(fields[0]); err != nil || len(hash) != sha256.Size {
+			return fmt.Errorf("%s:%d: invalid sha256 hash", store.path, lineNumber)
+		}
+		apiKey := APIKey{Name: fields[1]}
+		if len(fields) == 3 {
+			apiKey.Scopes = strings.Split(fields[2], ",")
+		}
+		keys[strings.ToLower(fields[0])] = apiKey
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.keys = keys
+	return nil
+}
+
+func (store *HashedFileKeyStore) Lookup(key string) (APIKey, bool) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	apiKey, exists := store.keys[HashAPIKey(key)]
+	return apiKey, exists
+}
+
+type APIKeyConfig struct {
+	Header string
+	// QueryParameter is checked if the header is missing, keys in urls tend to end up in logs
+	QueryParameter string
+	Store          KeyStore
+}
+
+func APIKeyAuth(config APIKeyConfig) Filter {
+	if config.Header == "" {
+		config.Header = DefaultAPIKeyHeader
+	}
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		key := r.Header.Get(config.Header)
+		if key == "" && config.QueryParameter != "" {
+			key = r.URL.Query().Get(config.QueryParameter)
+		}
+		apiKey, exists := config.Store.Lookup(key)
+		if key == "" || !exists {
+			httputils.Unauthorized(w, r)
+			return
+		}
+		ctx := WithScopes(WithPrincipal(r.Context(), Principal{Name: apiKey.Name}), apiKey.Scopes)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// RequireScopes answers 401 for unauthenticated requests and 403 if one of the scopes is missing
+func RequireScopes(scopes ...string) Filter {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if _, authenticated := GetPrincipal(r.Context()); !authenticated {
+			httputils.Unauthorized(w, r)
+			return
+		}
+		granted := Scopes(r.Context())
+		for _, scope := range scopes {
+			if !slices.Contains(granted, scope) {
+				httputils.Forbidden(w, r)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,84 @@
+package routing
+
+import (
+	go_http "github.com/mwildt/go-http"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func apiKeyRouter(store KeyStore) *Router {
+	return NewRouter(func(router Routing) {
+		router.Route(Path("/internal").Filter(APIKeyAuth(APIKeyConfig{QueryParameter: "api_key", Store: store})), func(router Routing) {
+			handler := func(writer http.ResponseWriter, request *http.Request) {
+				principal, _ := GetPrincipal(request.Context())
+				writer.Write([]byte(principal.Name + ":" + strings.Join(Scopes(request.Context()), ",")))
+			}
+			router.HandleFunc(Get("/read"), handler)
+			router.HandleFunc(Post("/write").Filter(RequireScopes("write")), handler)
+		})
+	})
+}
+
+func serveAPIKey(router *Router, method string, target string, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	router := apiKeyRouter(NewMemoryKeyStore(map[string]APIKey{
+		"reader-key": {Name: "reporting", Scopes: []string{"read"}},
+		"writer-key": {Name: "importer", Scopes: []string{"read", "write"}},
+	}))
+
+	recorder := serveAPIKey(router, "GET", "http://example.com/internal/read", "reader-key")
+	go_http.Assert(t, recorder.Code == 200, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, recorder.Body.String() == "reporting:read", "unexpected response body %s", recorder.Body.String())
+
+	recorder = serveAPIKey(router, "GET", "http://example.com/internal/read?api_key=writer-key", "")
+	go_http.Assert(t, recorder.Body.String() == "importer:read,write", "query parameter not used %s", recorder.Body.String())
+
+	go_http.Assert(t, serveAPIKey(router, "GET", "http://example.com/internal/read", "").Code == 401, "missing key accepted")
+	go_http.Assert(t, serveAPIKey(router, "GET", "http://example.com/internal/read", "unknown").Code == 401, "unknown key accepted")
+	go_http.Assert(t, serveAPIKey(router, "POST", "http://example.com/internal/write", "reader-key").Code == 403, "missing scope accepted")
+	go_http.Assert(t, serveAPIKey(router, "POST", "http://example.com/internal/write", "writer-key").Code == 200, "granted scope rejected")
+}
+
+func TestHashedFileKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	os.WriteFile(path, []byte("# service keys\n"+HashAPIKey("secret-1")+" billing read,write\n"+HashAPIKey("secret-2")+" audit\n"), 0600)
+	store, err := LoadHashedFileKeyStore(path)
+	go_http.AssertNoError(t, err, "loading key store failed")
+
+	apiKey, exists := store.Lookup("secret-1")
+	go_http.Assert(t, exists && apiKey.Name == "billing" && len(apiKey.Scopes) == 2, "unexpected key %v", apiKey)
+	apiKey, exists = store.Lookup("secret-2")
+	go_http.Assert(t, exists && apiKey.Name == "audit" && len(apiKey.Scopes) == 0, "unexpected key %v", apiKey)
+	_, exists = store.Lookup(HashAPIKey("secret-1"))
+	go_http.Assert(t, !exists, "hash must not be usable as key")
+
+	os.WriteFile(path, []byte("nothex billing\n"), 0600)
+	go_http.Assert(t, store.Reload() != nil, "invalid file accepted")
+}
+
+func TestRequireScopesWithoutAuthentication(t *testing.T) {
+	router := NewRouter(func(router Routing) {
+		router.HandleFunc(Get("/").Filter(RequireScopes("read")), func(writer http.ResponseWriter, request *http.Request) {})
+	})
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "http://example.com/", nil))
+	go_http.Assert(t, recorder.Code == 401, "unexpected response status %d", recorder.Code)
+}
+
+func TestLoadHashedFileKeyStoreError(t *testing.T) {
+	store, err := LoadHashedFileKeyStore(filepath.Join(t.TempDir(), "missing"))
+	go_http.Assert(t, store == nil && err != nil, "missing file must fail")
+}
@@ -0,0 +1,133 @@
+package routing
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mwildt/go-http/httputils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Authenticator interface {
+	Authenticate(username string, password string) (Principal, bool)
+}
+
+type BasicAuthConfig struct {
+	Realm         string
+	Authenticator Authenticator
+}
+
+func BasicAuth(config BasicAuthConfig) Filter {
+	if config.Realm == "" {
+		config.Realm = "Restricted"
+	}
+	challenge := fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", config.Realm)
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		username, password, ok := r.BasicAuth()
+		if ok {
+			if principal, authenticated := config.Authenticator.Authenticate(username, password); authenticated {
+				next(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+				return
+			}
+		}
+		w.Header().Set("WWW-Authenticate", challenge)
+		httputils.Unauthorized(w, r)
+	}
+}
+
+// StaticAuthenticator maps usernames to plain passwords
+type StaticAuthenticator map[string]string
+
+func (users StaticAuthenticator) Authenticate(username string, password string) (Principal, bool) {
+	expected, exists := users[username]
+	// compare digests, so neither the length of the password nor the existence of the user leaks
+	expectedDigest := sha256.Sum256([]byte(expected))
+	actualDigest := sha256.Sum256([]byte(password))
+	if subtle.ConstantTimeCompare(expectedDigest[:], actualDigest[:]) == 1 && exists {
+		return Principal{Name: username}, true
+	}
+	return Principal{}, false
+}
+
+var dummyBcryptHash = []byte("$2a$10$qJBSZ5oSDNsGiBZ7yH.stOEkxLRzi5rE.Qei8jiK/pYXdBTBHdJlC")
+
+// HtpasswdAuthenticator supports bcrypt ($2y$, $2a$, $2b$) and {SHA} entries of an htpasswd file
+type HtpasswdAuthenticator struct {
+	path    string
+	mutex   sync.RWMutex
+	entries map[string]string
+}
+
+func LoadHtpasswd(path string) (*HtpasswdAuthenticator, error) {
+	authenticator := &HtpasswdAuthenticator{path: path}
+	if err := authenticator.Reload(); err != nil {
+		return nil, err
+	}
+	return authenticator, nil
+}
+
+func (authenticator *HtpasswdAuthenticator) Reload() error {
+	file, err := os.Open(authenticator.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entries := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		username, hash, found := strings.Cut(line, ":")
+		if !found || username == "" {
+			return fmt.Errorf("%s:%d: expected '<user>:<hash>'", authenticator.path, lineNumber)
+		} else if !isBcrypt(hash) && !strings.HasPrefix(hash, "{SHA}") {
+			return fmt.Errorf("%s:%d: unsupported hash for user '%s', use bcrypt or {SHA}", authenticator.path, lineNumber, username)
+		}
+		entries[username] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	authenticator.mutex.Lock()
+	defer authenticator.mutex.Unlock()
+	authenticator.entries = entries
+	return nil
+}
+
+func (authenticator *HtpasswdAuthenticator) Authenticate(username string, password string) (Principal, bool) {
+	authenticator.mutex.RLock()
+	hash, exists := authenticator.entries[username]
+	authenticator.mutex.RUnlock()
+	if !exists {
+		// spend the time of a bcrypt comparison, so unknown users cannot be told apart by timing
+		bcrypt.CompareHashAndPassword(dummyBcryptHash, []byte(password))
+		return Principal{}, false
+	}
+	if isBcrypt(hash) {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return Principal{Name: username}, true
+		}
+	} else if expected, found := strings.CutPrefix(hash, "{SHA}"); found {
+		digest := sha1.Sum([]byte(password))
+		actual := base64.StdEncoding.EncodeToString(digest[:])
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) == 1 {
+			return Principal{Name: username}, true
+		}
+	}
+	return Principal{}, false
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2y$") || strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$")
+}
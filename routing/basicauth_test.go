@@ -0,0 +1,63 @@
+package routing
+
+import (
+	go_http "github.com/mwildt/go-http"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePrincipalName(writer http.ResponseWriter, request *http.Request) {
+	principal, _ := GetPrincipal(request.Context())
+	writer.Write([]byte(principal.Name))
+}
+
+func basicAuthRequest(username string, password string) *http.Request {
+	req := httptest.NewRequest("GET", "http://example.com/secret", nil)
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	return req
+}
+
+func TestBasicAuthStatic(t *testing.T) {
+	router := singleRoute(Get("/secret").Filter(BasicAuth(BasicAuthConfig{Realm: "admin", Authenticator: StaticAuthenticator{"alice": "wonderland"}})), writePrincipalName)
+
+	recorder := serve(router, basicAuthRequest("alice", "wonderland"))
+	go_http.Assert(t, recorder.Code == 200, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, recorder.Body.String() == "alice", "unexpected principal %s", recorder.Body.String())
+
+	for _, credentials := range [][2]string{{"alice", "wrong"}, {"bob", "wonderland"}, {"bob", ""}, {"", ""}} {
+		recorder = serve(router, basicAuthRequest(credentials[0], credentials[1]))
+		go_http.Assert(t, recorder.Code == 401, "unexpected response status %d", recorder.Code)
+		go_http.Assert(t, recorder.Header().Get("WWW-Authenticate") == `Basic realm="admin", charset="UTF-8"`, "unexpected challenge %s", recorder.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestBasicAuthHtpasswd(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("bcrypt-secret"), bcrypt.MinCost)
+	path := filepath.Join(t.TempDir(), ".htpasswd")
+	// {SHA} of "password"
+	os.WriteFile(path, []byte("# users\nalice:"+string(hash)+"\nbob:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0600)
+
+	authenticator, err := LoadHtpasswd(path)
+	go_http.AssertNoError(t, err, "loading htpasswd failed")
+	router := singleRoute(Get("/secret").Filter(BasicAuth(BasicAuthConfig{Realm: "admin", Authenticator: authenticator})), writePrincipalName)
+
+	go_http.Assert(t, serve(router, basicAuthRequest("alice", "bcrypt-secret")).Code == 200, "bcrypt user rejected")
+	go_http.Assert(t, serve(router, basicAuthRequest("alice", "wrong")).Code == 401, "wrong bcrypt password accepted")
+	go_http.Assert(t, serve(router, basicAuthRequest("bob", "password")).Code == 200, "sha user rejected")
+	go_http.Assert(t, serve(router, basicAuthRequest("bob", "wrong")).Code == 401, "wrong sha password accepted")
+
+	os.WriteFile(path, []byte("carol:$apr1$salt$hash\n"), 0600)
+	go_http.Assert(t, authenticator.Reload() != nil, "unsupported hash accepted")
+	go_http.Assert(t, serve(router, basicAuthRequest("bob", "password")).Code == 200, "entries must be kept on reload error")
+}
+
+func TestLoadHtpasswdError(t *testing.T) {
+	authenticator, err := LoadHtpasswd(filepath.Join(t.TempDir(), "missing"))
+	go_http.Assert(t, authenticator == nil && err != nil, "missing file must fail")
+}
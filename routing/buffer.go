@@ -0,0 +1,66 @@
+package routing
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+)
+
+// responseBuffer records a complete response, so filters can inspect it before it is sent
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: make(http.Header)}
+}
+
+func (buffer *responseBuffer) Header() http.Header {
+	return buffer.header
+}
+
+func (buffer *responseBuffer) WriteHeader(code int) {
+	if buffer.status == 0 {
+		buffer.status = code
+	}
+}
+
+func (buffer *responseBuffer) Write(data []byte) (int, error) {
+	if buffer.status == 0 {
+		buffer.status = http.StatusOK
+	}
+	return buffer.body.Write(data)
+}
+
+func (buffer *responseBuffer) statusCode() int {
+	if buffer.status == 0 {
+		return http.StatusOK
+	}
+	return buffer.status
+}
+
+func (buffer *responseBuffer) copyTo(w http.ResponseWriter) {
+	copyHeader(w.Header(), buffer.header)
+	w.WriteHeader(buffer.statusCode())
+	w.Write(buffer.body.Bytes())
+}
+
+// listHeaders are merged with values set by outer filters before the response was buffered
+var listHeaders = []string{"Vary", "Set-Cookie", "Link", "Access-Control-Expose-Headers"}
+
+// copyHeader copies a buffered header to the response, list headers are merged instead of replaced
+func copyHeader(dst http.Header, src http.Header) {
+	for key, values := range src {
+		if !slices.Contains(listHeaders, key) {
+			dst[key] = slices.Clone(values)
+			continue
+		}
+		for _, value := range values {
+			if !slices.Contains(dst[key], value) {
+				dst[key] = append(dst[key], value)
+			}
+		}
+	}
+}
@@ -1,9 +1,13 @@
 package routing
 
+import "net/http"
+
 type RouteBuilder struct {
 	path        Segments
 	methods     Methods
 	filterChain FilterChain
+	maxBodySize int64
+	policies    []Policy
 }
 
 func NewRouteBuilder() RouteBuilder {
@@ -12,11 +16,17 @@ func NewRouteBuilder() RouteBuilder {
 
 func (builder RouteBuilder) extend(extension RouteBuilder) RouteBuilder {
 
-	return RouteBuilder{
+	extended := RouteBuilder{
 		path:        builder.path.Extend(extension.path),
 		methods:     builder.methods.Extend(extension.methods),
 		filterChain: builder.filterChain.Extend(extension.filterChain),
+		maxBodySize: builder.maxBodySize,
+		policies:    append(append([]Policy{}, builder.policies...), extension.policies...),
+	}
+	if extension.maxBodySize != 0 {
+		extended.maxBodySize = extension.maxBodySize
 	}
+	return extended
 }
 
 func Filtering(filter Filter) RouteBuilder {
@@ -62,10 +72,43 @@ func (builder RouteBuilder) Method(methods ...string) RouteBuilder {
 }
 
 func (builder RouteBuilder) Filter(filter Filter) RouteBuilder {
-	builder.filterChain = append(builder.filterChain, filter)
+	builder.filterChain = builder.filterChain.Extend(FilterChain{filter})
+	return builder
+}
+
+// MaxBodySize limits the request body of the route, it is inherited by subrouters and
+// may be overridden there. A negative size removes an inherited limit.
+func (builder RouteBuilder) MaxBodySize(size int64) RouteBuilder {
+	builder.maxBodySize = size
+	return builder
+}
+
+// Require adds a policy, which is evaluated after all filters of the route, so after authentication.
+// Policies of subrouters are inherited, all of them have to allow the request.
+func (builder RouteBuilder) Require(policy Policy) RouteBuilder {
+	builder.policies = append(append([]Policy{}, builder.policies...), policy)
 	return builder
 }
 
 func (builder RouteBuilder) createMatcher() matcher {
 	return matcher{path: builder.path, methods: builder.methods}
 }
+
+func (builder RouteBuilder) createRoute(handlerFunc http.HandlerFunc) Route {
+	filterChain := builder.filterChain
+	if builder.maxBodySize > 0 {
+		filterChain = append(FilterChain{LimitBody(builder.maxBodySize)}, filterChain...)
+	}
+	var policy *Policy
+	if len(builder.policies) > 0 {
+		required := AllOf(builder.policies...)
+		policy = &required
+		filterChain = filterChain.Extend(FilterChain{required.Filter})
+	}
+	return Route{
+		matcher:     builder.createMatcher(),
+		filterChain: filterChain,
+		handlerFunc: filterChain.Build(handlerFunc),
+		policy:      policy,
+	}
+}
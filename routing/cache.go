@@ -0,0 +1,327 @@
+package routing
+
+import (
+	"container/list"
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type CacheConfig struct {
+	// MaxBytes bounds the approximate size of all cached responses, defaults to 32 MiB
+	MaxBytes int64
+	// Key identifies the resource, defaults to host and request uri
+	Key func(r *http.Request) string
+}
+
+type cacheEntry struct {
+	key     string
+	base    string
+	route   string
+	status  int
+	header  http.Header
+	body    []byte
+	size    int64
+	stored  time.Time
+	fresh   time.Time
+	stale   time.Time
+	element *list.Element
+}
+
+// cacheVariants holds the Vary header names of a resource and the number of its cached variants
+type cacheVariants struct {
+	names []string
+	count int
+}
+
+// ResponseCache is a shared in-memory cache for GET and HEAD responses with explicit freshness
+// (max-age or s-maxage). Responses marked no-store, no-cache or private, responses setting cookies
+// and responses varying on "*" are not cached. Responses to requests with credentials, that is an Authorization or
+// Cookie header or a principal, are only cached if marked public or s-maxage. Entries are evicted least recently used
+// once MaxBytes is exceeded. A hit skips the inner filters, so Filter must run after the authentication filters.
+type ResponseCache struct {
+	mutex      sync.Mutex
+	config     CacheConfig
+	entries    map[string]*cacheEntry
+	varies     map[string]*cacheVariants
+	lru        *list.List
+	size       int64
+	refreshing map[string]bool
+}
+
+func NewResponseCache(config CacheConfig) *ResponseCache {
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = 32 << 20
+	}
+	if config.Key == nil {
+		config.Key = func(r *http.Request) string {
+			return r.Host + r.URL.RequestURI()
+		}
+	}
+	return &ResponseCache{
+		config:     config,
+		entries:    make(map[string]*cacheEntry),
+		varies:     make(map[string]*cacheVariants),
+		lru:        list.New(),
+		refreshing: make(map[string]bool),
+	}
+}
+
+func (cache *ResponseCache) Filter(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	base := cache.config.Key(r)
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		buffer := newResponseBuffer()
+		next(buffer, r)
+		if buffer.statusCode() < 400 {
+			cache.purge(func(entry *cacheEntry) bool { return entry.base == base })
+		}
+		buffer.copyTo(w)
+		return
+	}
+	if requestDirectives := parseCacheControl(r.Header.Get("Cache-Control")); requestDirectives.has("no-store") {
+		w.Header().Set("X-Cache", "BYPASS")
+		next(w, r)
+		return
+	}
+
+	now := time.Now()
+	cache.mutex.Lock()
+	entry, found := cache.lookup(base, r, now)
+	revalidate := false
+	if found {
+		cache.lru.MoveToFront(entry.element)
+		if now.After(entry.fresh) && !cache.refreshing[entry.key] {
+			cache.refreshing[entry.key] = true
+			revalidate = true
+		}
+	}
+	cache.mutex.Unlock()
+
+	if found {
+		if now.After(entry.fresh) {
+			w.Header().Set("X-Cache", "STALE")
+		} else {
+			w.Header().Set("X-Cache", "HIT")
+		}
+		entry.writeTo(w, r, now)
+		if revalidate {
+			go cache.revalidate(entry, r.Clone(context.WithoutCancel(r.Context())), next)
+		}
+		return
+	}
+
+	w.Header().Set("X-Cache", "MISS")
+	if r.Method == http.MethodHead {
+		// a HEAD response has no body to serve later GET requests from
+		next(w, r)
+		return
+	}
+	buffer := newResponseBuffer()
+	next(buffer, r)
+	cache.store(base, r, buffer, now)
+	buffer.copyTo(w)
+}
+
+func (cache *ResponseCache) revalidate(stale *cacheEntry, r *http.Request, next http.HandlerFunc) {
+	defer func() {
+		// a panic would crash the server outside of the request, so it is logged and the stale entry is kept
+		if p := recover(); p != nil {
+			log.Printf("routing: revalidating %s failed: %v", stale.key, p)
+		}
+		cache.mutex.Lock()
+		delete(cache.refreshing, stale.key)
+		cache.mutex.Unlock()
+	}()
+	buffer := newResponseBuffer()
+	next(buffer, r)
+	cache.store(stale.base, r, buffer, time.Now())
+}
+
+// lookup must be called with the mutex held, stale entries past stale-while-revalidate are removed
+func hasCredentials(r *http.Request) bool {
+	_, authenticated := GetPrincipal(r.Context())
+	return authenticated || r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != ""
+}
+
+func (cache *ResponseCache) lookup(base string, r *http.Request, now time.Time) (*cacheEntry, bool) {
+	variants, known := cache.varies[base]
+	if !known {
+		return nil, false
+	}
+	entry, found := cache.entries[varyKey(base, variants.names, r.Header)]
+	if found && now.After(entry.stale) {
+		cache.remove(entry)
+		return nil, false
+	}
+	return entry, found
+}
+
+func (cache *ResponseCache) store(base string, r *http.Request, buffer *responseBuffer, now time.Time) {
+	if !cacheableStatus(buffer.statusCode()) {
+		return
+	}
+	directives := parseCacheControl(buffer.header.Get("Cache-Control"))
+	if directives.has("no-store") || directives.has("no-cache") || directives.has("private") || buffer.header.Get("Set-Cookie") != "" {
+		return
+	}
+	ttl, ok := directives.seconds("s-maxage")
+	if !ok {
+		ttl, ok = directives.seconds("max-age")
+	}
+	if !ok {
+		return
+	}
+	// responses to authenticated requests are personal unless marked otherwise
+	if hasCredentials(r) && !directives.has("public") && !directives.has("s-maxage") {
+		return
+	}
+	var names []string
+	for _, value := range buffer.header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name == "*" {
+				return
+			} else if name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	swr, _ := directives.seconds("stale-while-revalidate")
+
+	entry := &cacheEntry{
+		key:    varyKey(base, names, r.Header),
+		base:   base,
+		route:  GetRoutePattern(r.Context()),
+		status: buffer.statusCode(),
+		header: buffer.header.Clone(),
+		body:   buffer.body.Bytes(),
+		stored: now,
+		fresh:  now.Add(ttl),
+		stale:  now.Add(ttl + swr),
+	}
+	entry.size = int64(len(entry.key) + len(entry.body))
+	for name, values := range entry.header {
+		for _, value := range values {
+			entry.size += int64(len(name) + len(value))
+		}
+	}
+	if entry.size > cache.config.MaxBytes {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if previous, exists := cache.entries[entry.key]; exists {
+		cache.remove(previous)
+	}
+	if variants, exists := cache.varies[base]; exists && !slices.Equal(variants.names, names) {
+		cache.purgeLocked(func(other *cacheEntry) bool { return other.base == base })
+	}
+	if variants, exists := cache.varies[base]; exists {
+		variants.names = names
+		variants.count++
+	} else {
+		cache.varies[base] = &cacheVariants{names: names, count: 1}
+	}
+	entry.element = cache.lru.PushFront(entry)
+	cache.entries[entry.key] = entry
+	cache.size += entry.size
+	for cache.size > cache.config.MaxBytes {
+		cache.remove(cache.lru.Back().Value.(*cacheEntry))
+	}
+}
+
+// remove must be called with the mutex held
+func (cache *ResponseCache) remove(entry *cacheEntry) {
+	cache.lru.Remove(entry.element)
+	delete(cache.entries, entry.key)
+	cache.size -= entry.size
+	variants := cache.varies[entry.base]
+	variants.count--
+	if variants.count == 0 {
+		delete(cache.varies, entry.base)
+	}
+}
+
+func (cache *ResponseCache) purge(match func(entry *cacheEntry) bool) int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.purgeLocked(match)
+}
+
+func (cache *ResponseCache) purgeLocked(match func(entry *cacheEntry) bool) int {
+	purged := 0
+	for _, entry := range cache.entries {
+		if match(entry) {
+			cache.remove(entry)
+			purged++
+		}
+	}
+	return purged
+}
+
+// PurgePrefix removes all entries whose key starts with prefix and returns their number
+func (cache *ResponseCache) PurgePrefix(prefix string) int {
+	return cache.purge(func(entry *cacheEntry) bool { return strings.HasPrefix(entry.base, prefix) })
+}
+
+// PurgeRoute removes all entries recorded for a route pattern as returned by GetRoutePattern
+func (cache *ResponseCache) PurgeRoute(pattern string) int {
+	return cache.purge(func(entry *cacheEntry) bool { return entry.route == pattern })
+}
+
+func (entry *cacheEntry) writeTo(w http.ResponseWriter, r *http.Request, now time.Time) {
+	copyHeader(w.Header(), entry.header)
+	w.Header().Set("Age", strconv.Itoa(int(now.Sub(entry.stored).Seconds())))
+	w.WriteHeader(entry.status)
+	if r.Method != http.MethodHead {
+		w.Write(entry.body)
+	}
+}
+
+func varyKey(base string, names []string, header http.Header) string {
+	var key strings.Builder
+	key.WriteString(base)
+	for _, name := range names {
+		key.WriteString("\x00" + name + "=" + strings.Join(header.Values(name), ","))
+	}
+	return key.String()
+}
+
+func cacheableStatus(status int) bool {
+	switch status {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent, http.StatusMovedPermanently, http.StatusNotFound, http.StatusGone:
+		return true
+	}
+	return false
+}
+
+type cacheControl map[string]string
+
+func parseCacheControl(value string) cacheControl {
+	directives := make(cacheControl)
+	for _, directive := range strings.Split(value, ",") {
+		name, argument, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if name != "" {
+			directives[strings.ToLower(name)] = strings.Trim(argument, `"`)
+		}
+	}
+	return directives
+}
+
+func (directives cacheControl) has(name string) bool {
+	_, ok := directives[name]
+	return ok
+}
+
+func (directives cacheControl) seconds(name string) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(directives[name])
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
@@ -0,0 +1,160 @@
+package routing
+
+import (
+	go_http "github.com/mwildt/go-http"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// localizedItem responds in the requested language with the number of calls, so cached responses can be told apart
+func localizedItem(cacheControl string, calls *atomic.Int32) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		count := calls.Add(1)
+		writer.Header().Set("Cache-Control", cacheControl)
+		writer.Header().Set("Vary", "Accept-Language")
+		writer.Write([]byte(request.Header.Get("Accept-Language") + " " + strconv.Itoa(int(count))))
+	}
+}
+
+func itemRequest(method string, path string, language string) *http.Request {
+	req := httptest.NewRequest(method, "http://example.com"+path, nil)
+	if language != "" {
+		req.Header.Set("Accept-Language", language)
+	}
+	return req
+}
+
+func TestResponseCacheHitAndVary(t *testing.T) {
+	calls := &atomic.Int32{}
+	router := singleRoute(Path("/items/{id}").Filter(NewResponseCache(CacheConfig{}).Filter), localizedItem("max-age=60", calls))
+
+	miss := serve(router, itemRequest("GET", "/items/1", "de"))
+	go_http.Assert(t, miss.Header().Get("X-Cache") == "MISS" && miss.Body.String() == "de 1", "unexpected miss %s", miss.Body.String())
+	hit := serve(router, itemRequest("GET", "/items/1", "de"))
+	go_http.Assert(t, hit.Header().Get("X-Cache") == "HIT" && hit.Body.String() == "de 1", "unexpected hit %s", hit.Body.String())
+	go_http.Assert(t, hit.Header().Get("Age") == "0", "missing age header")
+
+	head := serve(router, itemRequest("HEAD", "/items/1", "de"))
+	go_http.Assert(t, head.Header().Get("X-Cache") == "HIT" && head.Body.Len() == 0, "HEAD not served from cache")
+
+	other := serve(router, itemRequest("GET", "/items/1", "en"))
+	go_http.Assert(t, other.Header().Get("X-Cache") == "MISS" && other.Body.String() == "en 2", "vary not respected %s", other.Body.String())
+
+	serve(router, itemRequest("DELETE", "/items/1", ""))
+	purged := serve(router, itemRequest("GET", "/items/1", "de"))
+	go_http.Assert(t, purged.Header().Get("X-Cache") == "MISS", "unsafe request did not invalidate")
+}
+
+func TestResponseCacheDirectives(t *testing.T) {
+	for _, cacheControl := range []string{"", "no-store", "private, max-age=60", "no-cache"} {
+		calls := &atomic.Int32{}
+		router := singleRoute(Path("/items/{id}").Filter(NewResponseCache(CacheConfig{}).Filter), localizedItem(cacheControl, calls))
+		serve(router, itemRequest("GET", "/items/1", ""))
+		serve(router, itemRequest("GET", "/items/1", ""))
+		go_http.Assert(t, calls.Load() == 2, "%q must not be cached", cacheControl)
+	}
+	calls := &atomic.Int32{}
+	router := singleRoute(Path("/items/{id}").Filter(NewResponseCache(CacheConfig{}).Filter), localizedItem("max-age=0, s-maxage=60", calls))
+	serve(router, itemRequest("GET", "/items/1", ""))
+	serve(router, itemRequest("GET", "/items/1", ""))
+	go_http.Assert(t, calls.Load() == 1, "s-maxage must take precedence")
+}
+
+func TestResponseCacheCredentials(t *testing.T) {
+	userFilter := func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if user := r.Header.Get("X-User"); user != "" {
+			r = r.WithContext(WithPrincipal(r.Context(), Principal{Name: user}))
+		}
+		next(w, r)
+	}
+	for _, cacheControl := range []string{"max-age=60", "public, max-age=60"} {
+		for _, header := range []string{"Cookie", "X-User"} {
+			calls := &atomic.Int32{}
+			router := singleRoute(Path("/items/{id}").Filter(userFilter).Filter(NewResponseCache(CacheConfig{}).Filter), localizedItem(cacheControl, calls))
+			for i := 0; i < 2; i++ {
+				req := itemRequest("GET", "/items/1", "")
+				req.Header.Set(header, "alice")
+				serve(router, req)
+			}
+			expected := map[string]int32{"max-age=60": 2, "public, max-age=60": 1}[cacheControl]
+			go_http.Assert(t, calls.Load() == expected, "%s with %s: handler called %d times", cacheControl, header, calls.Load())
+		}
+	}
+}
+
+func TestResponseCacheStaleWhileRevalidate(t *testing.T) {
+	calls := &atomic.Int32{}
+	router := singleRoute(Path("/items/{id}").Filter(NewResponseCache(CacheConfig{}).Filter), localizedItem("max-age=0, stale-while-revalidate=60", calls))
+	serve(router, itemRequest("GET", "/items/1", ""))
+
+	stale := serve(router, itemRequest("GET", "/items/1", ""))
+	go_http.Assert(t, stale.Header().Get("X-Cache") == "STALE" && stale.Body.String() == " 1", "stale response not served %s", stale.Body.String())
+	deadline := time.Now().Add(time.Second)
+	for calls.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	go_http.Assert(t, calls.Load() == 2, "no background revalidation")
+}
+
+func TestResponseCacheRevalidationPanic(t *testing.T) {
+	calls := &atomic.Int32{}
+	cache := NewResponseCache(CacheConfig{})
+	router := singleRoute(Path("/items/{id}").Filter(cache.Filter), func(writer http.ResponseWriter, request *http.Request) {
+		if calls.Add(1) > 1 {
+			panic("backend failed")
+		}
+		localizedItem("max-age=0, stale-while-revalidate=60", &atomic.Int32{})(writer, request)
+	})
+	revalidated := func(expected int32) bool {
+		cache.mutex.Lock()
+		defer cache.mutex.Unlock()
+		return calls.Load() == expected && len(cache.refreshing) == 0
+	}
+	serve(router, itemRequest("GET", "/items/1", ""))
+
+	for _, expected := range []int32{2, 3} {
+		stale := serve(router, itemRequest("GET", "/items/1", ""))
+		go_http.Assert(t, stale.Header().Get("X-Cache") == "STALE" && stale.Body.String() == " 1", "stale entry not kept %s", stale.Body.String())
+		deadline := time.Now().Add(time.Second)
+		for !revalidated(expected) && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		go_http.Assert(t, revalidated(expected), "revalidation did not finish")
+	}
+}
+
+func TestResponseCachePurgeAndEviction(t *testing.T) {
+	calls := &atomic.Int32{}
+	cache := NewResponseCache(CacheConfig{MaxBytes: 400})
+	router := singleRoute(Path("/items/{id}").Filter(cache.Filter), localizedItem("max-age=60", calls))
+
+	serve(router, itemRequest("GET", "/items/1", ""))
+	serve(router, itemRequest("GET", "/items/2", ""))
+	go_http.Assert(t, cache.PurgePrefix("example.com/items/1") == 1, "prefix purge failed")
+	go_http.Assert(t, cache.PurgeRoute("/items/{id}") == 1, "route purge failed")
+
+	for i := 0; i < 10; i++ {
+		serve(router, itemRequest("GET", "/items/"+strconv.Itoa(i), ""))
+	}
+	cache.mutex.Lock()
+	size, count := cache.size, len(cache.entries)
+	cache.mutex.Unlock()
+	go_http.Assert(t, size <= 400 && count < 10, "cache not bounded: %d bytes, %d entries", size, count)
+	recent := serve(router, itemRequest("GET", "/items/9", ""))
+	go_http.Assert(t, recent.Header().Get("X-Cache") == "HIT", "most recent entry evicted")
+}
+
+func TestResponseCacheKeepsOuterVary(t *testing.T) {
+	calls := &atomic.Int32{}
+	router := singleRoute(Path("/items/{id}").Filter(Compress(CompressionConfig{})).Filter(NewResponseCache(CacheConfig{}).Filter), localizedItem("max-age=60", calls))
+	for _, expected := range []string{"MISS", "HIT"} {
+		recorder := serve(router, itemRequest("GET", "/items/1", ""))
+		vary := recorder.Header().Values("Vary")
+		go_http.Assert(t, recorder.Header().Get("X-Cache") == expected, "expected %s", expected)
+		go_http.Assert(t, len(vary) == 2 && vary[0] == "Accept-Encoding", "%s: outer vary header lost %v", expected, vary)
+	}
+}
@@ -0,0 +1,83 @@
+package routing
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type CoalesceConfig struct {
+	// Headers are part of the default key next to host, path, query, principal and the Authorization and Cookie headers, e.g. Accept
+	Headers []string
+	// Key replaces the default key, it must distinguish requests of different users itself
+	Key func(r *http.Request) string
+}
+
+type flight struct {
+	done     chan struct{}
+	response *responseBuffer
+}
+
+// Coalesce lets only one of concurrent identical GET requests reach the handler, the others wait
+// for its response and receive a copy of it. Responses setting cookies are never shared, the
+// waiting requests then call the handler themselves. The default key includes the principal, so Coalesce
+// must run after the authentication filters to separate clients using other credentials, e.g. API keys.
+func Coalesce(config CoalesceConfig) Filter {
+	if config.Key == nil {
+		headers := append([]string{"Authorization", "Cookie"}, config.Headers...)
+		config.Key = func(r *http.Request) string {
+			principal, _ := GetPrincipal(r.Context())
+			var key strings.Builder
+			key.WriteString(r.Host + r.URL.Path + "?" + r.URL.RawQuery + "\x00" + principal.Name)
+			for _, name := range headers {
+				key.WriteString("\x00" + strings.Join(r.Header.Values(name), ","))
+			}
+			return key.String()
+		}
+	}
+	var mutex sync.Mutex
+	flights := make(map[string]*flight)
+
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if r.Method != http.MethodGet {
+			next(w, r)
+			return
+		}
+		key := config.Key(r)
+		mutex.Lock()
+		current, waiting := flights[key]
+		if !waiting {
+			current = &flight{done: make(chan struct{})}
+			flights[key] = current
+		}
+		mutex.Unlock()
+
+		if waiting {
+			select {
+			case <-current.done:
+			case <-r.Context().Done():
+				return
+			}
+			if current.response == nil || len(current.response.header.Values("Set-Cookie")) > 0 {
+				// the handler of the first request panicked or the response is bound to its client
+				next(w, r)
+				return
+			}
+			copyHeader(w.Header(), current.response.header)
+			w.WriteHeader(current.response.statusCode())
+			w.Write(current.response.body.Bytes())
+			return
+		}
+
+		defer func() {
+			mutex.Lock()
+			delete(flights, key)
+			mutex.Unlock()
+			close(current.done)
+		}()
+		buffer := newResponseBuffer()
+		next(buffer, r)
+		current.response = buffer
+		buffer.copyTo(w)
+	}
+}
@@ -0,0 +1,151 @@
+package routing
+
+import (
+	go_http "github.com/mwildt/go-http"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowReport counts its calls and responds once release is closed
+func slowReport(calls *atomic.Int32, release chan struct{}) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		count := calls.Add(1)
+		<-release
+		writer.Header().Set("X-Call", strconv.Itoa(int(count)))
+		writer.Write([]byte("report " + request.URL.RawQuery))
+	}
+}
+
+func reportRequest(header string, value string) *http.Request {
+	req := httptest.NewRequest("GET", "http://example.com/report?year=2024", nil)
+	req.Header.Set(header, value)
+	return req
+}
+
+func serveConcurrently(router *Router, requests ...*http.Request) []*httptest.ResponseRecorder {
+	recorders := make([]*httptest.ResponseRecorder, len(requests))
+	var group sync.WaitGroup
+	for i, req := range requests {
+		group.Add(1)
+		go func(i int, req *http.Request) {
+			defer group.Done()
+			recorders[i] = serve(router, req)
+		}(i, req)
+	}
+	group.Wait()
+	return recorders
+}
+
+// countArrivals counts the requests before they reach the filters behind it
+func countArrivals(arrived *atomic.Int32) Filter {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		arrived.Add(1)
+		next(w, r)
+	}
+}
+
+// releaseOnArrival closes release once count requests arrived
+func releaseOnArrival(release chan struct{}, arrived *atomic.Int32, count int32) {
+	go func() {
+		for arrived.Load() < count {
+			time.Sleep(time.Millisecond)
+		}
+		close(release)
+	}()
+}
+
+func TestCoalesceIdenticalRequests(t *testing.T) {
+	calls := &atomic.Int32{}
+	arrived, release := &atomic.Int32{}, make(chan struct{})
+	router := singleRoute(Get("/report").Filter(countArrivals(arrived)).Filter(Coalesce(CoalesceConfig{})), slowReport(calls, release))
+	releaseOnArrival(release, arrived, 5)
+
+	var requests []*http.Request
+	for _, tenant := range []string{"a", "b", "c", "d", "e"} {
+		requests = append(requests, reportRequest("X-Tenant", tenant))
+	}
+	recorders := serveConcurrently(router, requests...)
+	go_http.Assert(t, calls.Load() == 1, "handler called %d times", calls.Load())
+	for _, recorder := range recorders {
+		go_http.Assert(t, recorder.Code == 200 && recorder.Body.String() == "report year=2024", "unexpected response %d %s", recorder.Code, recorder.Body.String())
+		go_http.Assert(t, recorder.Header().Get("X-Call") == "1", "headers not fanned out")
+	}
+}
+
+func TestCoalesceKeyHeaders(t *testing.T) {
+	calls := &atomic.Int32{}
+	arrived, release := &atomic.Int32{}, make(chan struct{})
+	router := singleRoute(Get("/report").Filter(countArrivals(arrived)).Filter(Coalesce(CoalesceConfig{Headers: []string{"X-Tenant"}})), slowReport(calls, release))
+	releaseOnArrival(release, arrived, 4)
+
+	serveConcurrently(router, reportRequest("X-Tenant", "a"), reportRequest("X-Tenant", "b"), reportRequest("X-Tenant", "a"), reportRequest("X-Tenant", "b"))
+	go_http.Assert(t, calls.Load() == 2, "requests of different tenants must not be coalesced, handler called %d times", calls.Load())
+}
+
+func TestCoalesceSeparatesPrincipals(t *testing.T) {
+	calls := &atomic.Int32{}
+	arrived, release := &atomic.Int32{}, make(chan struct{})
+	router := singleRoute(Get("/report").Filter(countArrivals(arrived)).Filter(Coalesce(CoalesceConfig{})), func(writer http.ResponseWriter, request *http.Request) {
+		slowReport(calls, release)(writer, request)
+		writer.Write([]byte(" for " + request.Header.Get("Authorization")))
+	})
+	releaseOnArrival(release, arrived, 3)
+
+	recorders := serveConcurrently(router, reportRequest("Authorization", "Bearer alice"), reportRequest("Authorization", "Bearer bob"), reportRequest("Cookie", "session=carol"))
+	go_http.Assert(t, calls.Load() == 3, "requests of different principals must not be coalesced, handler called %d times", calls.Load())
+	go_http.Assert(t, recorders[0].Body.String() == "report year=2024 for Bearer alice", "unexpected response %s", recorders[0].Body.String())
+	go_http.Assert(t, recorders[1].Body.String() == "report year=2024 for Bearer bob", "unexpected response %s", recorders[1].Body.String())
+}
+
+func TestCoalesceDoesNotShareCookies(t *testing.T) {
+	calls := &atomic.Int32{}
+	arrived, release := &atomic.Int32{}, make(chan struct{})
+	router := singleRoute(Get("/report").Filter(countArrivals(arrived)).Filter(Coalesce(CoalesceConfig{})), func(writer http.ResponseWriter, request *http.Request) {
+		count := calls.Add(1)
+		<-release
+		http.SetCookie(writer, &http.Cookie{Name: "session", Value: "call-" + strconv.Itoa(int(count))})
+	})
+	releaseOnArrival(release, arrived, 3)
+
+	recorders := serveConcurrently(router, reportRequest("Accept", "text/plain"), reportRequest("Accept", "text/plain"), reportRequest("Accept", "text/plain"))
+	go_http.Assert(t, calls.Load() == 3, "responses setting cookies must not be shared, handler called %d times", calls.Load())
+	cookies := map[string]bool{}
+	for _, recorder := range recorders {
+		cookies[recorder.Header().Get("Set-Cookie")] = true
+	}
+	go_http.Assert(t, len(cookies) == 3, "cookie fanned out to waiters %v", cookies)
+}
+
+func TestCoalesceSeparatesAPIKeys(t *testing.T) {
+	calls := &atomic.Int32{}
+	arrived, release := &atomic.Int32{}, make(chan struct{})
+	store := NewMemoryKeyStore(map[string]APIKey{"reporting-key": {Name: "reporting"}, "billing-key": {Name: "billing"}})
+	router := singleRoute(Get("/report").Filter(countArrivals(arrived)).Filter(APIKeyAuth(APIKeyConfig{Store: store})).Filter(Coalesce(CoalesceConfig{})), func(writer http.ResponseWriter, request *http.Request) {
+		slowReport(calls, release)(writer, request)
+		principal, _ := GetPrincipal(request.Context())
+		writer.Write([]byte(" for " + principal.Name))
+	})
+	releaseOnArrival(release, arrived, 2)
+
+	recorders := serveConcurrently(router, reportRequest("X-API-Key", "reporting-key"), reportRequest("X-API-Key", "billing-key"))
+	go_http.Assert(t, calls.Load() == 2, "requests of different api keys must not be coalesced, handler called %d times", calls.Load())
+	go_http.Assert(t, recorders[0].Body.String() == "report year=2024 for reporting", "unexpected response %s", recorders[0].Body.String())
+	go_http.Assert(t, recorders[1].Body.String() == "report year=2024 for billing", "unexpected response %s", recorders[1].Body.String())
+}
+
+func TestCoalesceSeparatesHosts(t *testing.T) {
+	calls := &atomic.Int32{}
+	arrived, release := &atomic.Int32{}, make(chan struct{})
+	router := singleRoute(Get("/report").Filter(countArrivals(arrived)).Filter(Coalesce(CoalesceConfig{})), slowReport(calls, release))
+	releaseOnArrival(release, arrived, 2)
+
+	other := reportRequest("Accept", "text/plain")
+	other.Host = "other.example.com"
+	serveConcurrently(router, reportRequest("Accept", "text/plain"), other)
+	go_http.Assert(t, calls.Load() == 2, "requests of different hosts must not be coalesced, handler called %d times", calls.Load())
+}
@@ -0,0 +1,221 @@
+package routing
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var DefaultUncompressedTypes = []string{
+	"image/", "video/", "audio/", "font/woff", "font/woff2",
+	"application/zip", "application/gzip", "application/x-gzip", "application/x-7z-compressed",
+	"application/x-rar-compressed", "application/x-bzip2", "application/zstd", "application/octet-stream",
+}
+
+type CompressionConfig struct {
+	Level int
+	// responses smaller than MinSize are sent uncompressed, unless they are flushed
+	MinSize int
+	// content type prefixes which are already compressed, "image/svg+xml" is always compressed
+	SkipContentTypes []string
+}
+
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+func Compress(config CompressionConfig) Filter {
+	if config.Level == 0 {
+		config.Level = gzip.DefaultCompression
+	}
+	if config.MinSize == 0 {
+		config.MinSize = 1024
+	}
+	if config.SkipContentTypes == nil {
+		config.SkipContentTypes = DefaultUncompressedTypes
+	}
+	// gzip and zlib accept the same levels, so the pools cannot fail once the level is checked
+	if _, err := gzip.NewWriterLevel(io.Discard, config.Level); err != nil {
+		panic(err)
+	}
+	pools := map[string]*sync.Pool{
+		"gzip": {New: func() any {
+			writer, _ := gzip.NewWriterLevel(io.Discard, config.Level)
+			return writer
+		}},
+		"deflate": {New: func() any {
+			writer, _ := zlib.NewWriterLevel(io.Discard, config.Level)
+			return writer
+		}},
+	}
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next(w, r)
+			return
+		}
+		writer := &compressWriter{ResponseWriter: w, config: &config, encoding: encoding, pool: pools[encoding]}
+		defer writer.Close()
+		next(writer, r)
+	}
+}
+
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if q, err := strconv.ParseFloat(value, 64); err == nil {
+				quality = q
+			}
+		}
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			qualities[name] = quality
+		}
+	}
+	best, bestQuality := "", 0.0
+	for _, encoding := range []string{"gzip", "deflate"} {
+		quality, exists := qualities[encoding]
+		if !exists {
+			quality, exists = qualities["*"]
+		}
+		if exists && quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+	config     *CompressionConfig
+	encoding   string
+	pool       *sync.Pool
+	status     int
+	buffer     []byte
+	decided    bool
+	compressor compressor
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.status != 0 || cw.decided {
+		return
+	}
+	if code < 200 {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.status = code
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(data []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if !cw.decided {
+		cw.buffer = append(cw.buffer, data...)
+		if len(cw.buffer) < cw.config.MinSize {
+			return len(data), nil
+		}
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if cw.compressor != nil {
+		return cw.compressor.Write(data)
+	}
+	return cw.ResponseWriter.Write(data)
+}
+
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		cw.decide(true)
+	}
+	if cw.compressor != nil {
+		cw.compressor.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buffer) == 0 {
+			// nothing was written by the handler, the server sends its default response
+			return nil
+		}
+		cw.decide(len(cw.buffer) >= cw.config.MinSize)
+	}
+	if cw.compressor == nil {
+		return nil
+	}
+	err := cw.compressor.Close()
+	cw.compressor.Reset(io.Discard)
+	cw.pool.Put(cw.compressor)
+	cw.compressor = nil
+	return err
+}
+
+// decide writes the header and the buffered data, either compressed or as is
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+	header := cw.Header()
+	if header.Get("Content-Type") == "" && len(cw.buffer) > 0 {
+		header.Set("Content-Type", http.DetectContentType(cw.buffer))
+	}
+	if compress && header.Get("Content-Encoding") == "" && !cw.skipContentType(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		// the compressed body is another representation, so a strong etag only holds as weak etag
+		if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			header.Set("ETag", "W/"+etag)
+		}
+		cw.compressor = cw.pool.Get().(compressor)
+		cw.compressor.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	buffer := cw.buffer
+	cw.buffer = nil
+	if len(buffer) == 0 {
+		return nil
+	} else if cw.compressor != nil {
+		_, err := cw.compressor.Write(buffer)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buffer)
+	return err
+}
+
+func (cw *compressWriter) skipContentType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(strings.ToLower(contentType), ";")
+	mediaType = strings.TrimSpace(mediaType)
+	if mediaType == "image/svg+xml" {
+		return false
+	}
+	for _, skipped := range cw.config.SkipContentTypes {
+		if strings.HasPrefix(mediaType, skipped) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package routing
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	go_http "github.com/mwildt/go-http"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func compressRouter(contentType string, body string) *Router {
+	return NewRouter(func(router Routing) {
+		router.HandleFunc(Get("/data").Filter(Compress(CompressionConfig{MinSize: 16})), func(writer http.ResponseWriter, request *http.Request) {
+			if contentType != "" {
+				writer.Header().Set("Content-Type", contentType)
+			}
+			writer.Header().Set("Content-Length", "123")
+			writer.Write([]byte(body))
+		})
+	})
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	for acceptEncoding, expected := range map[string]string{
+		"":                         "",
+		"gzip":                     "gzip",
+		"deflate, gzip":            "gzip",
+		"gzip;q=0.5, deflate":      "deflate",
+		"gzip;q=0, deflate;q=0":    "",
+		"*":                        "gzip",
+		"br, identity":             "",
+		"GZIP;q=0.8, deflate;q=.9": "deflate",
+	} {
+		actual := negotiateEncoding(acceptEncoding)
+		go_http.Assert(t, actual == expected, "unexpected encoding '%s' for '%s'", actual, acceptEncoding)
+	}
+}
+
+func TestCompressGzip(t *testing.T) {
+	body := strings.Repeat("compress me ", 20)
+	req := httptest.NewRequest("GET", "http://example.com/data", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	recorder := httptest.NewRecorder()
+	compressRouter("text/plain", body).ServeHTTP(recorder, req)
+
+	go_http.Assert(t, recorder.Header().Get("Content-Encoding") == "gzip", "unexpected encoding '%s'", recorder.Header().Get("Content-Encoding"))
+	go_http.Assert(t, recorder.Header().Get("Content-Length") == "", "content length must be removed")
+	go_http.Assert(t, recorder.Header().Get("Vary") == "Accept-Encoding", "missing vary header")
+	reader, err := gzip.NewReader(recorder.Body)
+	go_http.AssertNoError(t, err, "invalid gzip body")
+	decompressed, _ := io.ReadAll(reader)
+	go_http.Assert(t, string(decompressed) == body, "unexpected body %s", decompressed)
+}
+
+func TestCompressDeflate(t *testing.T) {
+	body := strings.Repeat("compress me ", 20)
+	req := httptest.NewRequest("GET", "http://example.com/data", nil)
+	req.Header.Set("Accept-Encoding", "deflate")
+	recorder := httptest.NewRecorder()
+	compressRouter("", body).ServeHTTP(recorder, req)
+
+	go_http.Assert(t, recorder.Header().Get("Content-Encoding") == "deflate", "unexpected encoding '%s'", recorder.Header().Get("Content-Encoding"))
+	go_http.Assert(t, strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain"), "content type not detected from uncompressed body")
+	reader, err := zlib.NewReader(recorder.Body)
+	go_http.AssertNoError(t, err, "invalid deflate body")
+	decompressed, _ := io.ReadAll(reader)
+	go_http.Assert(t, string(decompressed) == body, "unexpected body %s", decompressed)
+}
+
+func TestCompressSkipped(t *testing.T) {
+	for _, test := range []struct{ contentType, body string }{
+		{"text/plain", "tiny"},
+		{"image/png", strings.Repeat("x", 100)},
+	} {
+		req := httptest.NewRequest("GET", "http://example.com/data", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		recorder := httptest.NewRecorder()
+		compressRouter(test.contentType, test.body).ServeHTTP(recorder, req)
+
+		go_http.Assert(t, recorder.Header().Get("Content-Encoding") == "", "unexpected encoding for %s", test.contentType)
+		go_http.Assert(t, recorder.Body.String() == test.body, "unexpected body %s", recorder.Body.String())
+		go_http.Assert(t, recorder.Header().Get("Vary") == "Accept-Encoding", "missing vary header")
+	}
+}
+
+func TestCompressFlush(t *testing.T) {
+	router := NewRouter(func(router Routing) {
+		router.HandleFunc(Get("/stream").Filter(Compress(CompressionConfig{})), func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("Content-Type", "text/event-stream")
+			writer.Write([]byte("data: 1\n\n"))
+			writer.(http.Flusher).Flush()
+			writer.Write([]byte("data: 2\n\n"))
+		})
+	})
+	req := httptest.NewRequest("GET", "http://example.com/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	go_http.Assert(t, recorder.Flushed, "response was not flushed")
+	go_http.Assert(t, recorder.Header().Get("Content-Encoding") == "gzip", "flushed response must be compressed")
+	reader, err := gzip.NewReader(recorder.Body)
+	go_http.AssertNoError(t, err, "invalid gzip body")
+	decompressed, _ := io.ReadAll(reader)
+	go_http.Assert(t, string(decompressed) == "data: 1\n\ndata: 2\n\n", "unexpected body %s", decompressed)
+}
+
+func TestCompressInvalidLevel(t *testing.T) {
+	defer func() {
+		go_http.Assert(t, recover() != nil, "invalid compression level must be rejected")
+	}()
+	Compress(CompressionConfig{Level: 42})
+}
+
+func TestCompressWeakensETag(t *testing.T) {
+	body := strings.Repeat("compress me ", 20)
+	router := NewRouter(func(router Routing) {
+		router.HandleFunc(Get("/data").Filter(Compress(CompressionConfig{MinSize: 16})).Filter(ETag(ETagConfig{})), func(writer http.ResponseWriter, request *http.Request) {
+			writer.Write([]byte(body))
+		})
+	})
+	request := func(acceptEncoding string, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "http://example.com/data", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		req.Header.Set("If-None-Match", ifNoneMatch)
+		return serve(router, req)
+	}
+
+	plain := request("", "")
+	compressed := request("gzip", "")
+	go_http.Assert(t, compressed.Header().Get("ETag") == "W/"+plain.Header().Get("ETag"), "unexpected etag %q of compressed response", compressed.Header().Get("ETag"))
+	recorder := request("gzip", compressed.Header().Get("ETag"))
+	go_http.Assert(t, recorder.Code == 304, "weakened etag must match, got %d", recorder.Code)
+}
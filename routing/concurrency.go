@@ -0,0 +1,153 @@
+package routing
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mwildt/go-http/httputils"
+)
+
+type ConcurrencyConfig struct {
+	// Limit of requests served at the same time
+	Limit int
+	// QueueSize requests may wait up to MaxWait for a free slot, MaxWait 0 waits until the request is cancelled
+	QueueSize  int
+	MaxWait    time.Duration
+	RetryAfter time.Duration
+	Adaptive   *AdaptiveConcurrency
+}
+
+// AdaptiveConcurrency adjusts the limit between MinLimit and MaxLimit: it is decreased by Backoff
+// when a request takes longer than TargetLatency, and increased by one after a full limit of fast requests.
+type AdaptiveConcurrency struct {
+	MinLimit      int
+	MaxLimit      int
+	TargetLatency time.Duration
+	Backoff       float64
+}
+
+type ConcurrencyLimiter struct {
+	config    ConcurrencyConfig
+	mutex     sync.Mutex
+	limit     int
+	inFlight  int
+	successes int
+	queue     []chan struct{}
+}
+
+func NewConcurrencyLimiter(config ConcurrencyConfig) *ConcurrencyLimiter {
+	if config.Limit <= 0 {
+		config.Limit = 1
+	}
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = time.Second
+	}
+	if adaptive := config.Adaptive; adaptive != nil {
+		adaptive.MinLimit = max(adaptive.MinLimit, 1)
+		if adaptive.MaxLimit < config.Limit {
+			adaptive.MaxLimit = config.Limit
+		}
+		if adaptive.Backoff <= 0 || adaptive.Backoff >= 1 {
+			adaptive.Backoff = 0.9
+		}
+	}
+	return &ConcurrencyLimiter{config: config, limit: config.Limit}
+}
+
+// ConcurrencyLimit creates a limiter per filter, share a ConcurrencyLimiter to limit several routes together
+func ConcurrencyLimit(config ConcurrencyConfig) Filter {
+	return NewConcurrencyLimiter(config).Filter
+}
+
+func (limiter *ConcurrencyLimiter) Filter(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if !limiter.acquire(r.Context()) {
+		w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(limiter.config.RetryAfter))))
+		httputils.Send(w, r, http.StatusServiceUnavailable)
+		return
+	}
+	start := time.Now()
+	defer func() {
+		limiter.release(time.Since(start))
+	}()
+	next(w, r)
+}
+
+func (limiter *ConcurrencyLimiter) Limit() int {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	return limiter.limit
+}
+
+func (limiter *ConcurrencyLimiter) InFlight() int {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	return limiter.inFlight
+}
+
+func (limiter *ConcurrencyLimiter) acquire(ctx context.Context) bool {
+	limiter.mutex.Lock()
+	if limiter.inFlight < limiter.limit {
+		limiter.inFlight++
+		limiter.mutex.Unlock()
+		return true
+	} else if len(limiter.queue) >= limiter.config.QueueSize {
+		limiter.mutex.Unlock()
+		return false
+	}
+	ticket := make(chan struct{})
+	limiter.queue = append(limiter.queue, ticket)
+	limiter.mutex.Unlock()
+
+	var timeout <-chan time.Time
+	if limiter.config.MaxWait > 0 {
+		timer := time.NewTimer(limiter.config.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-ticket:
+		return true
+	case <-timeout:
+	case <-ctx.Done():
+	}
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	for i, queued := range limiter.queue {
+		if queued == ticket {
+			limiter.queue = append(limiter.queue[:i], limiter.queue[i+1:]...)
+			return false
+		}
+	}
+	// the slot was granted while giving up
+	return true
+}
+
+func (limiter *ConcurrencyLimiter) release(latency time.Duration) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	limiter.adapt(latency)
+	limiter.inFlight--
+	for limiter.inFlight < limiter.limit && len(limiter.queue) > 0 {
+		limiter.inFlight++
+		close(limiter.queue[0])
+		limiter.queue = limiter.queue[1:]
+	}
+}
+
+func (limiter *ConcurrencyLimiter) adapt(latency time.Duration) {
+	adaptive := limiter.config.Adaptive
+	if adaptive == nil {
+		return
+	}
+	if latency > adaptive.TargetLatency {
+		limiter.limit = max(adaptive.MinLimit, int(float64(limiter.limit)*adaptive.Backoff))
+		limiter.successes = 0
+	} else if limiter.successes++; limiter.successes >= limiter.limit {
+		limiter.limit = min(adaptive.MaxLimit, limiter.limit+1)
+		limiter.successes = 0
+	}
+}
@@ -0,0 +1,87 @@
+package routing
+
+import (
+	go_http "github.com/mwildt/go-http"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimitSheds(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyConfig{Limit: 1, QueueSize: 1, MaxWait: time.Second, RetryAfter: 2 * time.Second})
+	started := make(chan struct{}, 3)
+	unblock := make(chan struct{})
+	router := NewRouter(func(router Routing) {
+		router.HandleFunc(Get("/work").Filter(limiter.Filter), func(writer http.ResponseWriter, request *http.Request) {
+			started <- struct{}{}
+			<-unblock
+		})
+	})
+
+	recorders := make([]*httptest.ResponseRecorder, 2)
+	var wg sync.WaitGroup
+	for i := range recorders {
+		recorders[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(recorder *httptest.ResponseRecorder) {
+			defer wg.Done()
+			router.ServeHTTP(recorder, httptest.NewRequest("GET", "http://example.com/work", nil))
+		}(recorders[i])
+	}
+	<-started
+	for queued := 0; queued == 0; {
+		time.Sleep(time.Millisecond)
+		limiter.mutex.Lock()
+		queued = len(limiter.queue)
+		limiter.mutex.Unlock()
+	}
+
+	// one request in flight, one queued, the third is shed
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "http://example.com/work", nil))
+	go_http.Assert(t, recorder.Code == 503, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, recorder.Header().Get("Retry-After") == "2", "unexpected retry after %s", recorder.Header().Get("Retry-After"))
+
+	close(unblock)
+	wg.Wait()
+	for _, recorder := range recorders {
+		go_http.Assert(t, recorder.Code == 200, "unexpected response status %d", recorder.Code)
+	}
+	go_http.Assert(t, len(started) == 1, "queued request was not served")
+	go_http.Assert(t, limiter.InFlight() == 0, "unexpected in flight %d", limiter.InFlight())
+}
+
+func TestConcurrencyLimitMaxWait(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyConfig{Limit: 1, QueueSize: 1, MaxWait: 10 * time.Millisecond})
+	go_http.Assert(t, limiter.acquire(httptest.NewRequest("GET", "/", nil).Context()), "first acquire failed")
+	go_http.Assert(t, !limiter.acquire(httptest.NewRequest("GET", "/", nil).Context()), "queued acquire must time out")
+	go_http.Assert(t, len(limiter.queue) == 0, "timed out ticket not removed")
+	limiter.release(0)
+	go_http.Assert(t, limiter.InFlight() == 0, "unexpected in flight %d", limiter.InFlight())
+}
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyConfig{
+		Limit:    10,
+		Adaptive: &AdaptiveConcurrency{MinLimit: 2, MaxLimit: 11, TargetLatency: 100 * time.Millisecond, Backoff: 0.5},
+	})
+	ctx := httptest.NewRequest("GET", "/", nil).Context()
+
+	limiter.acquire(ctx)
+	limiter.release(time.Second)
+	go_http.Assert(t, limiter.Limit() == 5, "unexpected limit %d", limiter.Limit())
+
+	limiter.acquire(ctx)
+	limiter.release(time.Second)
+	limiter.acquire(ctx)
+	limiter.release(time.Second)
+	go_http.Assert(t, limiter.Limit() == 2, "limit must not drop below minimum %d", limiter.Limit())
+
+	for i := 0; i < 2; i++ {
+		limiter.acquire(ctx)
+		limiter.release(time.Millisecond)
+	}
+	go_http.Assert(t, limiter.Limit() == 3, "unexpected limit %d", limiter.Limit())
+}
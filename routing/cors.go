@@ -0,0 +1,157 @@
+package routing
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mwildt/go-http/httputils"
+)
+
+type CORSConfig struct {
+	// exact origins, "*" or wildcard subdomains like "https://*.example.com"
+	AllowedOrigins   []string
+	AllowOriginFunc  func(origin string) bool
+	AllowedMethods   Methods
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// CORS panics if credentials are allowed for any origin, as every site could then make credentialed requests
+func CORS(config CORSConfig) Filter {
+	if config.AllowCredentials && config.allowsAnyOrigin() {
+		panic("routing: CORS does not allow credentials for origin \"*\", list the origins or use AllowOriginFunc")
+	}
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		w.Header().Add("Vary", "Origin")
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin == "" {
+			next(w, r)
+			return
+		} else if !config.allowsOrigin(origin) {
+			if preflight {
+				httputils.Send(w, r, http.StatusForbidden)
+			} else {
+				next(w, r)
+			}
+			return
+		}
+
+		if !preflight {
+			config.setOriginHeaders(w, origin)
+			if len(config.ExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(config.ExposedHeaders, ", "))
+			}
+			next(w, r)
+			return
+		}
+
+		methods := config.methods(GetAllowedMethods(r.Context()))
+		if !methods.contains(r.Header.Get("Access-Control-Request-Method")) {
+			httputils.Send(w, r, http.StatusForbidden)
+			return
+		}
+		requestedHeaders := parseHeaderList(r.Header.Get("Access-Control-Request-Headers"))
+		if !config.allowsHeaders(requestedHeaders) {
+			httputils.Send(w, r, http.StatusForbidden)
+			return
+		}
+
+		config.setOriginHeaders(w, origin)
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		if len(requestedHeaders) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(requestedHeaders, ", "))
+		}
+		if config.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(config.MaxAge.Seconds())))
+		}
+		httputils.Send(w, r, http.StatusNoContent)
+	}
+}
+
+func (config CORSConfig) setOriginHeaders(w http.ResponseWriter, origin string) {
+	if config.allowsAnyOrigin() {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if config.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (config CORSConfig) allowsAnyOrigin() bool {
+	for _, allowed := range config.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func (config CORSConfig) allowsOrigin(origin string) bool {
+	for _, allowed := range config.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		} else if prefix, suffix, wildcard := strings.Cut(allowed, "*"); wildcard &&
+			len(origin) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return config.AllowOriginFunc != nil && config.AllowOriginFunc(origin)
+}
+
+// methods restricts the configured methods to those the router has routes for
+func (config CORSConfig) methods(routed Methods) Methods {
+	if len(config.AllowedMethods) == 0 {
+		return routed
+	} else if len(routed) == 0 {
+		return config.AllowedMethods
+	}
+	methods := make(Methods, 0)
+	for _, method := range config.AllowedMethods {
+		if routed.contains(method) {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+func (config CORSConfig) allowsHeaders(headers []string) bool {
+	if len(config.AllowedHeaders) == 0 {
+		return true
+	}
+	for _, header := range headers {
+		allowed := false
+		for _, allowedHeader := range config.AllowedHeaders {
+			if allowedHeader == "*" || strings.EqualFold(allowedHeader, header) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+func parseHeaderList(value string) []string {
+	headers := make([]string, 0)
+	for _, header := range strings.Split(value, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, http.CanonicalHeaderKey(header))
+		}
+	}
+	return headers
+}
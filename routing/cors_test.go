@@ -0,0 +1,127 @@
+package routing
+
+import (
+	go_http "github.com/mwildt/go-http"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func corsRouter(config CORSConfig) *Router {
+	return NewRouter(func(router Routing) {
+		router.Route(Path("/api").Filter(CORS(config)), func(router Routing) {
+			router.HandleFunc(Get("/items/{id}"), func(writer http.ResponseWriter, request *http.Request) {
+				writer.Write([]byte("GET"))
+			})
+			router.HandleFunc(Delete("/items/{id}"), func(writer http.ResponseWriter, request *http.Request) {
+				writer.Write([]byte("DELETE"))
+			})
+		})
+	}, DefaultNotFound())
+}
+
+func preflight(origin string, method string, headers string) *http.Request {
+	req := httptest.NewRequest("OPTIONS", "http://api.example.com/api/items/1", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		req.Header.Set("Access-Control-Request-Headers", headers)
+	}
+	return req
+}
+
+func TestCORSPreflightWithoutOptionsRoute(t *testing.T) {
+	router := corsRouter(CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, MaxAge: time.Hour})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, preflight("https://app.example.com", "DELETE", "content-type, x-token"))
+
+	go_http.Assert(t, recorder.Code == 204, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, recorder.Header().Get("Access-Control-Allow-Origin") == "https://app.example.com", "unexpected allow origin '%s'", recorder.Header().Get("Access-Control-Allow-Origin"))
+	go_http.Assert(t, recorder.Header().Get("Access-Control-Allow-Methods") == "GET, DELETE, OPTIONS", "unexpected allow methods '%s'", recorder.Header().Get("Access-Control-Allow-Methods"))
+	go_http.Assert(t, recorder.Header().Get("Access-Control-Allow-Headers") == "Content-Type, X-Token", "unexpected allow headers '%s'", recorder.Header().Get("Access-Control-Allow-Headers"))
+	go_http.Assert(t, recorder.Header().Get("Access-Control-Max-Age") == "3600", "unexpected max age '%s'", recorder.Header().Get("Access-Control-Max-Age"))
+}
+
+func TestCORSPreflightRejected(t *testing.T) {
+	router := corsRouter(CORSConfig{
+		AllowedOrigins: []string{"https://*.example.com"},
+		AllowedMethods: Methods{"GET"},
+		AllowedHeaders: []string{"Content-Type"},
+	})
+
+	for _, req := range []*http.Request{
+		preflight("https://evil.com", "GET", ""),
+		preflight("https://example.com", "GET", ""),
+		preflight("https://app.example.com", "DELETE", ""),
+		preflight("https://app.example.com", "PUT", ""),
+		preflight("https://app.example.com", "GET", "X-Token"),
+	} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		go_http.Assert(t, recorder.Code == 403, "unexpected response status %d", recorder.Code)
+		go_http.Assert(t, recorder.Header().Get("Access-Control-Allow-Origin") == "", "unexpected allow origin")
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, preflight("https://a.b.example.com", "GET", "content-type"))
+	go_http.Assert(t, recorder.Code == 204, "unexpected response status %d", recorder.Code)
+}
+
+func TestCORSActualRequest(t *testing.T) {
+	router := corsRouter(CORSConfig{
+		AllowedOrigins:   []string{"https://*.other.org"},
+		AllowCredentials: true,
+		ExposedHeaders:   []string{"X-Total"},
+	})
+
+	req := httptest.NewRequest("GET", "http://api.example.com/api/items/1", nil)
+	req.Header.Set("Origin", "https://app.other.org")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	go_http.Assert(t, recorder.Body.String() == "GET", "unexpected response body %s", recorder.Body.String())
+	go_http.Assert(t, recorder.Header().Get("Access-Control-Allow-Origin") == "https://app.other.org", "credentials require the origin to be echoed")
+	go_http.Assert(t, recorder.Header().Get("Access-Control-Allow-Credentials") == "true", "missing allow credentials")
+	go_http.Assert(t, recorder.Header().Get("Access-Control-Expose-Headers") == "X-Total", "missing expose headers")
+	go_http.Assert(t, recorder.Header().Get("Vary") == "Origin", "missing vary header")
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	router := corsRouter(CORSConfig{AllowedOrigins: []string{"*"}})
+
+	req := httptest.NewRequest("GET", "http://api.example.com/api/items/1", nil)
+	req.Header.Set("Origin", "https://other.org")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	go_http.Assert(t, recorder.Header().Get("Access-Control-Allow-Origin") == "*", "unexpected allow origin")
+	go_http.Assert(t, recorder.Header().Get("Access-Control-Allow-Credentials") == "", "unexpected allow credentials")
+
+	defer func() {
+		go_http.Assert(t, recover() != nil, "credentials for any origin must be rejected")
+	}()
+	CORS(CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+}
+
+func TestCORSOriginFunc(t *testing.T) {
+	router := corsRouter(CORSConfig{AllowOriginFunc: func(origin string) bool { return origin == "https://func.org" }})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, preflight("https://func.org", "GET", ""))
+	go_http.Assert(t, recorder.Code == 204, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, recorder.Header().Get("Access-Control-Allow-Origin") == "https://func.org", "unexpected allow origin")
+}
+
+func TestAutomaticOptions(t *testing.T) {
+	router := corsRouter(CORSConfig{})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("OPTIONS", "http://example.com/api/items/1", nil))
+	go_http.Assert(t, recorder.Code == 204, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, recorder.Header().Get("Allow") == "GET, DELETE, OPTIONS", "unexpected allow header '%s'", recorder.Header().Get("Allow"))
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("OPTIONS", "http://example.com/unknown", nil))
+	go_http.Assert(t, recorder.Code == 404, "unexpected response status %d", recorder.Code)
+}
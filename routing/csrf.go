@@ -0,0 +1,159 @@
+package routing
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"slices"
+
+	"github.com/mwildt/go-http/httputils"
+)
+
+const (
+	contextCSRFToken = contextKey("router.http.csrfToken")
+
+	csrfTokenLength = 32
+)
+
+type CSRFConfig struct {
+	CookieName string
+	CookiePath string
+	HeaderName string
+	FieldName  string
+	// Insecure allows the cookie over plain http, SameSite defaults to lax
+	Insecure bool
+	SameSite http.SameSite
+	// TrustedOrigins may send unsafe requests in addition to the origin of the request itself
+	TrustedOrigins []string
+	SafeMethods    Methods
+	Exempt         func(r *http.Request) bool
+}
+
+func WithCSRFToken(c context.Context, token string) context.Context {
+	return context.WithValue(c, contextCSRFToken, token)
+}
+
+// CSRFToken returns a masked token for forms and headers, it is different for every request
+func CSRFToken(c context.Context) string {
+	if value := c.Value(contextCSRFToken); value != nil {
+		return value.(string)
+	} else {
+		return ""
+	}
+}
+
+// ExemptRoutes exempts the routes with the given templates, e.g. "/webhooks/{provider}"
+func ExemptRoutes(patterns ...string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		return slices.Contains(patterns, GetRoutePattern(r.Context()))
+	}
+}
+
+// CSRF implements the double submit cookie pattern: unsafe requests need to send the token of the
+// cookie in a header or form field, and must not come from another site.
+func CSRF(config CSRFConfig) Filter {
+	if config.CookieName == "" {
+		config.CookieName = "csrf_token"
+	}
+	if config.CookiePath == "" {
+		config.CookiePath = "/"
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = "X-CSRF-Token"
+	}
+	if config.FieldName == "" {
+		config.FieldName = "csrf_token"
+	}
+	if config.SameSite == 0 {
+		config.SameSite = http.SameSiteLaxMode
+	}
+	if config.SafeMethods == nil {
+		config.SafeMethods = Methods{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace}
+	}
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		token, valid := []byte(nil), false
+		if cookie, err := r.Cookie(config.CookieName); err == nil {
+			token, valid = decodeCSRFToken(cookie.Value)
+		}
+		if !valid {
+			token = make([]byte, csrfTokenLength)
+			if _, err := rand.Read(token); err != nil {
+				panic(err)
+			}
+			http.SetCookie(w, &http.Cookie{
+				Name:     config.CookieName,
+				Value:    base64.RawURLEncoding.EncodeToString(token),
+				Path:     config.CookiePath,
+				Secure:   !config.Insecure,
+				HttpOnly: true,
+				SameSite: config.SameSite,
+			})
+		}
+		w.Header().Add("Vary", "Cookie")
+		r = r.WithContext(WithCSRFToken(r.Context(), maskCSRFToken(token)))
+
+		if config.SafeMethods.contains(r.Method) || (config.Exempt != nil && config.Exempt(r)) {
+			next(w, r)
+			return
+		}
+		if !valid || !config.sameOrigin(r) {
+			httputils.Forbidden(w, r)
+			return
+		}
+		submitted := r.Header.Get(config.HeaderName)
+		if submitted == "" {
+			submitted = r.PostFormValue(config.FieldName)
+		}
+		if actual, ok := unmaskCSRFToken(submitted); !ok || subtle.ConstantTimeCompare(actual, token) != 1 {
+			httputils.Forbidden(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (config CSRFConfig) sameOrigin(r *http.Request) bool {
+	if r.Header.Get("Sec-Fetch-Site") == "cross-site" {
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	scheme := "http://"
+	if isHTTPS(r) {
+		scheme = "https://"
+	}
+	return origin == scheme+r.Host || slices.Contains(config.TrustedOrigins, origin)
+}
+
+func decodeCSRFToken(value string) ([]byte, bool) {
+	token, err := base64.RawURLEncoding.DecodeString(value)
+	return token, err == nil && len(token) == csrfTokenLength
+}
+
+// maskCSRFToken xors the token with a random pad, so the token in responses changes on every request (BREACH)
+func maskCSRFToken(token []byte) string {
+	masked := make([]byte, 2*csrfTokenLength)
+	if _, err := rand.Read(masked[:csrfTokenLength]); err != nil {
+		panic(err)
+	}
+	for i := 0; i < csrfTokenLength; i++ {
+		masked[csrfTokenLength+i] = masked[i] ^ token[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+func unmaskCSRFToken(value string) ([]byte, bool) {
+	masked, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(masked) != 2*csrfTokenLength {
+		return nil, false
+	}
+	token := make([]byte, csrfTokenLength)
+	for i := 0; i < csrfTokenLength; i++ {
+		token[i] = masked[i] ^ masked[csrfTokenLength+i]
+	}
+	return token, true
+}
@@ -0,0 +1,95 @@
+package routing
+
+import (
+	go_http "github.com/mwildt/go-http"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func csrfRouter() *Router {
+	return NewRouter(func(router Routing) {
+		router.Route(Filtering(CSRF(CSRFConfig{TrustedOrigins: []string{"https://admin.example.com"}, Exempt: ExemptRoutes("/hooks/{provider}")})), func(router Routing) {
+			router.HandleFunc(Get("/form"), func(writer http.ResponseWriter, request *http.Request) {
+				writer.Write([]byte(CSRFToken(request.Context())))
+			})
+			router.HandleFunc(Post("/form"), func(writer http.ResponseWriter, request *http.Request) {
+				writer.Write([]byte("SAVED"))
+			})
+			router.HandleFunc(Post("/hooks/{provider}"), func(writer http.ResponseWriter, request *http.Request) {
+				writer.Write([]byte("HOOK"))
+			})
+		})
+	})
+}
+
+func fetchCSRFToken(t *testing.T, router *Router) (*http.Cookie, string) {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "http://example.com/form", nil))
+	cookies := recorder.Result().Cookies()
+	go_http.Assert(t, len(cookies) == 1 && cookies[0].HttpOnly && cookies[0].Secure, "missing secure csrf cookie")
+	return cookies[0], recorder.Body.String()
+}
+
+func TestCSRFTokenValidation(t *testing.T) {
+	router := csrfRouter()
+	cookie, token := fetchCSRFToken(t, router)
+	_, otherToken := fetchCSRFToken(t, router)
+
+	serve := func(configure func(req *http.Request)) int {
+		req := httptest.NewRequest("POST", "http://example.com/form", nil)
+		req.AddCookie(cookie)
+		configure(req)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	go_http.Assert(t, serve(func(req *http.Request) { req.Header.Set("X-CSRF-Token", token) }) == 200, "header token rejected")
+	go_http.Assert(t, serve(func(req *http.Request) {}) == 403, "missing token accepted")
+	go_http.Assert(t, serve(func(req *http.Request) { req.Header.Set("X-CSRF-Token", otherToken) }) == 403, "token of other cookie accepted")
+	go_http.Assert(t, serve(func(req *http.Request) {
+		req.Header.Set("X-CSRF-Token", token)
+		req.Header.Set("Origin", "https://evil.com")
+	}) == 403, "foreign origin accepted")
+	go_http.Assert(t, serve(func(req *http.Request) {
+		req.Header.Set("X-CSRF-Token", token)
+		req.Header.Set("Origin", "https://admin.example.com")
+	}) == 200, "trusted origin rejected")
+	go_http.Assert(t, serve(func(req *http.Request) {
+		req.Header.Set("X-CSRF-Token", token)
+		req.Header.Set("Sec-Fetch-Site", "cross-site")
+	}) == 403, "cross site request accepted")
+
+	req := httptest.NewRequest("POST", "http://example.com/form", strings.NewReader(url.Values{"csrf_token": {token}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	go_http.Assert(t, recorder.Code == 200, "form token rejected")
+}
+
+func TestCSRFTokenIsMasked(t *testing.T) {
+	router := csrfRouter()
+	cookie, token := fetchCSRFToken(t, router)
+
+	req := httptest.NewRequest("GET", "http://example.com/form", nil)
+	req.AddCookie(cookie)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	go_http.Assert(t, len(recorder.Result().Cookies()) == 0, "valid cookie must not be replaced")
+	go_http.Assert(t, recorder.Body.String() != token, "masked token must change per request")
+
+	first, _ := unmaskCSRFToken(token)
+	second, _ := unmaskCSRFToken(recorder.Body.String())
+	go_http.Assert(t, string(first) == string(second), "masked tokens must share the cookie token")
+}
+
+func TestCSRFExemptRoute(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	csrfRouter().ServeHTTP(recorder, httptest.NewRequest("POST", "http://example.com/hooks/github", nil))
+	go_http.Assert(t, recorder.Code == 200, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, recorder.Body.String() == "HOOK", "unexpected response body %s", recorder.Body.String())
+}
@@ -0,0 +1,65 @@
+package routing
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mwildt/go-http/httputils"
+)
+
+const DefaultMaxDecompressedSize = 10 << 20
+
+type DecompressionConfig struct {
+	// MaxSize limits the decompressed body, reading beyond fails with *http.MaxBytesError
+	MaxSize int64
+}
+
+type decompressingReader struct {
+	io.Reader
+	decompressor io.Closer
+	body         io.Closer
+}
+
+func (reader decompressingReader) Close() error {
+	reader.decompressor.Close()
+	return reader.body.Close()
+}
+
+func Decompress(config DecompressionConfig) Filter {
+	if config.MaxSize == 0 {
+		config.MaxSize = DefaultMaxDecompressedSize
+	}
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == "identity" {
+			next(w, r)
+			return
+		}
+
+		var decompressor io.ReadCloser
+		var err error
+		switch encoding {
+		case "gzip", "x-gzip":
+			decompressor, err = gzip.NewReader(r.Body)
+		case "deflate":
+			decompressor, err = zlib.NewReader(r.Body)
+		default:
+			w.Header().Set("Accept-Encoding", "gzip, deflate")
+			httputils.Send(w, r, http.StatusUnsupportedMediaType)
+			return
+		}
+		if err != nil {
+			httputils.BadRequest(w, r)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, decompressingReader{decompressor, decompressor, r.Body}, config.MaxSize)
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+		next(w, r)
+	}
+}
@@ -0,0 +1,75 @@
+package routing
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	go_http "github.com/mwildt/go-http"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var decompressRoute = Post("/upload").Filter(Decompress(DecompressionConfig{MaxSize: 1024}))
+
+func echoUpload(writer http.ResponseWriter, request *http.Request) {
+	body, err := io.ReadAll(request.Body)
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		writer.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+	writer.Write(body)
+}
+
+func TestDecompressRequestBody(t *testing.T) {
+	var gzipped, deflated bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	gzipWriter.Write([]byte("hello gzip"))
+	gzipWriter.Close()
+	zlibWriter := zlib.NewWriter(&deflated)
+	zlibWriter.Write([]byte("hello deflate"))
+	zlibWriter.Close()
+
+	for encoding, test := range map[string]struct {
+		body     []byte
+		expected string
+	}{
+		"gzip":    {gzipped.Bytes(), "hello gzip"},
+		"deflate": {deflated.Bytes(), "hello deflate"},
+		"":        {[]byte("plain"), "plain"},
+	} {
+		req := httptest.NewRequest("POST", "http://example.com/upload", bytes.NewReader(test.body))
+		req.Header.Set("Content-Encoding", encoding)
+		recorder := serve(singleRoute(decompressRoute, echoUpload), req)
+		go_http.Assert(t, recorder.Body.String() == test.expected, "unexpected response body '%s' for '%s'", recorder.Body.String(), encoding)
+	}
+}
+
+func TestDecompressLimit(t *testing.T) {
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	gzipWriter.Write([]byte(strings.Repeat("0", 1<<20)))
+	gzipWriter.Close()
+
+	req := httptest.NewRequest("POST", "http://example.com/upload", &gzipped)
+	req.Header.Set("Content-Encoding", "gzip")
+	recorder := serve(singleRoute(decompressRoute, echoUpload), req)
+	go_http.Assert(t, recorder.Code == 413, "unexpected response status %d", recorder.Code)
+}
+
+func TestDecompressUnsupported(t *testing.T) {
+	req := httptest.NewRequest("POST", "http://example.com/upload", strings.NewReader("data"))
+	req.Header.Set("Content-Encoding", "br")
+	recorder := serve(singleRoute(decompressRoute, echoUpload), req)
+	go_http.Assert(t, recorder.Code == 415, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, recorder.Header().Get("Accept-Encoding") == "gzip, deflate", "missing accept encoding header")
+
+	req = httptest.NewRequest("POST", "http://example.com/upload", strings.NewReader("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	recorder = serve(singleRoute(decompressRoute, echoUpload), req)
+	go_http.Assert(t, recorder.Code == 400, "unexpected response status %d", recorder.Code)
+}
@@ -0,0 +1,54 @@
+package routing
+
+import (
+	"net/http"
+
+	"github.com/mwildt/go-http/httputils"
+)
+
+type ETagConfig struct {
+	// Weak marks computed etags as weak, e.g. if the representation is not byte-for-byte stable
+	Weak bool
+}
+
+// ETag buffers successful GET and HEAD responses, computes an ETag from the body unless the handler set one,
+// and answers conditional requests with 304 or 412. HEAD requests reach the handler as GET, so both get the same ETag.
+// Handlers of unsafe methods or expensive resources can check preconditions themselves with httputils.CheckPreconditions.
+func ETag(config ETagConfig) Filter {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next(w, r)
+			return
+		}
+		buffer := newResponseBuffer()
+		if r.Method == http.MethodHead {
+			// the handler answers as for GET, so the etag is computed from the same body
+			get := r.Clone(r.Context())
+			get.Method = http.MethodGet
+			next(buffer, get)
+		} else {
+			next(buffer, r)
+		}
+		if buffer.statusCode() != http.StatusOK {
+			buffer.copyTo(w)
+			return
+		}
+
+		etag := buffer.header.Get("ETag")
+		if etag == "" {
+			if config.Weak {
+				etag = httputils.WeakETag(buffer.body.Bytes())
+			} else {
+				etag = httputils.StrongETag(buffer.body.Bytes())
+			}
+		}
+		lastModified, _ := http.ParseTime(buffer.header.Get("Last-Modified"))
+
+		copyHeader(w.Header(), buffer.header)
+		if httputils.CheckPreconditions(w, r, etag, lastModified) {
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(buffer.body.Bytes())
+	}
+}
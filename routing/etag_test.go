@@ -0,0 +1,89 @@
+package routing
+
+import (
+	go_http "github.com/mwildt/go-http"
+	"github.com/mwildt/go-http/httputils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func etagRouter(config ETagConfig) *Router {
+	return NewRouter(func(router Routing) {
+		router.HandleFunc(Get("/doc").Filter(ETag(config)), func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+			writer.Write([]byte("document"))
+		})
+		router.HandleFunc(Get("/versioned").Filter(ETag(config)), func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("ETag", `"v7"`)
+			writer.Write([]byte("versioned"))
+		})
+		router.HandleFunc(Get("/missing").Filter(ETag(config)), func(writer http.ResponseWriter, request *http.Request) {
+			writer.WriteHeader(http.StatusNotFound)
+		})
+	})
+}
+
+func serveConditional(router *Router, path string, header string, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "http://example.com"+path, nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestETagComputed(t *testing.T) {
+	router := etagRouter(ETagConfig{})
+	recorder := serveConditional(router, "/doc", "", "")
+	etag := recorder.Header().Get("ETag")
+	go_http.Assert(t, recorder.Code == 200 && recorder.Body.String() == "document", "unexpected response %d", recorder.Code)
+	go_http.Assert(t, len(etag) > 2 && etag[0] == '"', "missing strong etag %q", etag)
+
+	recorder = serveConditional(router, "/doc", "If-None-Match", `"other", `+etag)
+	go_http.Assert(t, recorder.Code == 304 && recorder.Body.Len() == 0, "expected 304, got %d", recorder.Code)
+	go_http.Assert(t, recorder.Header().Get("ETag") == etag, "304 must carry the etag")
+
+	recorder = serveConditional(router, "/doc", "If-Match", `"other"`)
+	go_http.Assert(t, recorder.Code == 412, "expected 412, got %d", recorder.Code)
+
+	recorder = serveConditional(router, "/doc", "If-Modified-Since", "Mon, 02 Jan 2006 15:04:05 GMT")
+	go_http.Assert(t, recorder.Code == 304, "expected 304 for If-Modified-Since, got %d", recorder.Code)
+	recorder = serveConditional(router, "/doc", "If-Modified-Since", "Sun, 01 Jan 2006 15:04:05 GMT")
+	go_http.Assert(t, recorder.Code == 200, "expected 200 for older If-Modified-Since, got %d", recorder.Code)
+}
+
+func TestETagWeakAndSupplied(t *testing.T) {
+	router := etagRouter(ETagConfig{Weak: true})
+	recorder := serveConditional(router, "/doc", "", "")
+	etag := recorder.Header().Get("ETag")
+	go_http.Assert(t, etag[:2] == "W/", "expected weak etag %q", etag)
+	recorder = serveConditional(router, "/doc", "If-None-Match", etag[2:])
+	go_http.Assert(t, recorder.Code == 304, "weak comparison failed %d", recorder.Code)
+	recorder = serveConditional(router, "/doc", "If-Match", etag)
+	go_http.Assert(t, recorder.Code == 412, "weak etags must fail If-Match %d", recorder.Code)
+
+	recorder = serveConditional(router, "/versioned", "If-None-Match", `"v7"`)
+	go_http.Assert(t, recorder.Code == 304, "handler etag not used %d", recorder.Code)
+	recorder = serveConditional(router, "/missing", "If-None-Match", "*")
+	go_http.Assert(t, recorder.Code == 404, "non 200 responses must pass through %d", recorder.Code)
+}
+
+func TestETagHead(t *testing.T) {
+	router := NewRouter(func(router Routing) {
+		router.HandleFunc(Method("HEAD").Path("/doc").Filter(ETag(ETagConfig{})), func(writer http.ResponseWriter, request *http.Request) {
+			if request.Method == http.MethodGet {
+				writer.Write([]byte("document"))
+			}
+		})
+	})
+	etag := httputils.StrongETag([]byte("document"))
+
+	head := serve(router, httptest.NewRequest("HEAD", "http://example.com/doc", nil))
+	go_http.Assert(t, head.Code == 200 && head.Header().Get("ETag") == etag, "unexpected head etag %q", head.Header().Get("ETag"))
+	req := httptest.NewRequest("HEAD", "http://example.com/doc", nil)
+	req.Header.Set("If-None-Match", etag)
+	head = serve(router, req)
+	go_http.Assert(t, head.Code == 304, "expected 304 for head, got %d", head.Code)
+}
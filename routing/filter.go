@@ -18,5 +18,7 @@ func (chain FilterChain) Build(handler http.HandlerFunc) http.HandlerFunc {
 }
 
 func (chain FilterChain) Extend(chain2 FilterChain) FilterChain {
-	return append(chain, chain2...)
+	// always copy, routes extending the same chain must not share its backing array
+	extended := make(FilterChain, 0, len(chain)+len(chain2))
+	return append(append(extended, chain...), chain2...)
 }
@@ -45,3 +45,23 @@ func TestSingletonFilterChain(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestExtendedChainsAreIndependent(t *testing.T) {
+	var logs []string
+	logFilter := func(value string) Filter {
+		return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+			logs = append(logs, value)
+			next(w, r)
+		}
+	}
+
+	router := NewRouter()
+	subrouter := router.Route(Path("/api").Filter(logFilter("a")).Filter(logFilter("b")).Filter(logFilter("c")))
+	subrouter.HandleFunc(Get("/x").Filter(logFilter("x")), func(writer http.ResponseWriter, request *http.Request) {})
+	subrouter.HandleFunc(Get("/y").Filter(logFilter("y")), func(writer http.ResponseWriter, request *http.Request) {})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://example.com/api/x", nil))
+	if len(logs) != 4 || logs[3] != "x" {
+		t.Errorf("unexpected filters %v", logs)
+	}
+}
@@ -0,0 +1,238 @@
+package routing
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mwildt/go-http/httputils"
+)
+
+var ErrIdempotencyKeyUnknown = errors.New("unknown idempotency key")
+
+type RecordedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+type IdempotencyRecord struct {
+	Fingerprint string
+	// Response is nil as long as the first request is in progress
+	Response *RecordedResponse
+}
+
+// IdempotencyStore must reserve keys atomically, as concurrent duplicates are detected by Begin
+type IdempotencyStore interface {
+	// Begin reserves the key for a new request, if the key is already known the existing record is returned with false
+	Begin(key string, fingerprint string, now time.Time) (IdempotencyRecord, bool, error)
+	Get(key string) (IdempotencyRecord, error)
+	Complete(key string, response RecordedResponse) error
+	// Release removes a reservation, so the request can be retried
+	Release(key string) error
+}
+
+type IdempotencyConfig struct {
+	Header string
+	// Methods defaults to POST and PATCH
+	Methods Methods
+	Store   IdempotencyStore
+	// Wait is how long a duplicate waits for the first request to finish before getting a 409, zero does not wait
+	Wait        time.Duration
+	MaxBodySize int64
+	Fingerprint func(r *http.Request, body []byte) string
+	// Scope separates the keys of different clients, see IdempotencyScope for the default.
+	// Routes accepting several authentication schemes with overlapping principal names need their own Scope.
+	Scope KeyFunc
+}
+
+// IdempotencyScope scopes keys by principal name, and requests without principal by client IP
+func IdempotencyScope(r *http.Request) string {
+	if principal, ok := GetPrincipal(r.Context()); ok && principal.Name != "" {
+		return "principal:" + principal.Name
+	}
+	return KeyByIP()(r)
+}
+
+func RequestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n"+r.Header.Get("Content-Type")+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Idempotency records the first response for an Idempotency-Key and replays it for retries.
+// Keys are scoped by Scope and route pattern. Server errors are not recorded, so they can be retried.
+func Idempotency(config IdempotencyConfig) Filter {
+	if config.Header == "" {
+		config.Header = "Idempotency-Key"
+	}
+	if len(config.Methods) == 0 {
+		config.Methods = Methods{http.MethodPost, http.MethodPatch}
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryIdempotencyStore(24 * time.Hour)
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = 1 << 20
+	}
+	if config.Fingerprint == nil {
+		config.Fingerprint = RequestFingerprint
+	}
+	if config.Scope == nil {
+		config.Scope = IdempotencyScope
+	}
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		idempotencyKey := r.Header.Get(config.Header)
+		if idempotencyKey == "" || !config.Methods.contains(r.Method) {
+			next(w, r)
+			return
+		}
+		if len(idempotencyKey) > 255 {
+			httputils.BadRequest(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, config.MaxBodySize+1))
+		r.Body.Close()
+		if err != nil {
+			httputils.BadRequest(w, r)
+			return
+		} else if int64(len(body)) > config.MaxBodySize {
+			httputils.Send(w, r, http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := config.Scope(r) + "\x00" + GetRoutePattern(r.Context()) + "\x00" + idempotencyKey
+		fingerprint := config.Fingerprint(r, body)
+
+		record, created, err := config.Store.Begin(key, fingerprint, time.Now())
+		if err != nil {
+			httputils.InternalServerError(w, r)
+			return
+		}
+		if !created {
+			replayIdempotent(w, r, config, key, fingerprint, record)
+			return
+		}
+
+		buffer := newResponseBuffer()
+		completed := false
+		defer func() {
+			if !completed {
+				config.Store.Release(key)
+			}
+		}()
+		next(buffer, r)
+
+		if buffer.statusCode() >= 500 {
+			config.Store.Release(key)
+		} else {
+			config.Store.Complete(key, RecordedResponse{Status: buffer.statusCode(), Header: buffer.header.Clone(), Body: buffer.body.Bytes()})
+		}
+		completed = true
+		buffer.copyTo(w)
+	}
+}
+
+func replayIdempotent(w http.ResponseWriter, r *http.Request, config IdempotencyConfig, key string, fingerprint string, record IdempotencyRecord) {
+	if record.Fingerprint != fingerprint {
+		httputils.Send(w, r, http.StatusUnprocessableEntity)
+		return
+	}
+	deadline := time.Now().Add(config.Wait)
+	for record.Response == nil {
+		if !time.Now().Before(deadline) {
+			httputils.Send(w, r, http.StatusConflict)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(min(10*time.Millisecond, time.Until(deadline))):
+		}
+		var err error
+		if record, err = config.Store.Get(key); err == ErrIdempotencyKeyUnknown {
+			// the first request failed and released the key
+			httputils.Send(w, r, http.StatusConflict)
+			return
+		} else if err != nil {
+			httputils.InternalServerError(w, r)
+			return
+		}
+	}
+	copyHeader(w.Header(), record.Response.Header)
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.Response.Status)
+	w.Write(record.Response.Body)
+}
+
+type idempotencyEntry struct {
+	record  IdempotencyRecord
+	expires time.Time
+}
+
+type MemoryIdempotencyStore struct {
+	mutex     sync.Mutex
+	entries   map[string]*idempotencyEntry
+	ttl       time.Duration
+	lastSweep time.Time
+}
+
+// NewMemoryIdempotencyStore keeps records for ttl after the request started
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{entries: make(map[string]*idempotencyEntry), ttl: ttl}
+}
+
+func (store *MemoryIdempotencyStore) Begin(key string, fingerprint string, now time.Time) (IdempotencyRecord, bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if now.Sub(store.lastSweep) > store.ttl {
+		store.lastSweep = now
+		for key, entry := range store.entries {
+			if now.After(entry.expires) {
+				delete(store.entries, key)
+			}
+		}
+	}
+	if entry, exists := store.entries[key]; exists && now.Before(entry.expires) {
+		return entry.record, false, nil
+	}
+	record := IdempotencyRecord{Fingerprint: fingerprint}
+	store.entries[key] = &idempotencyEntry{record: record, expires: now.Add(store.ttl)}
+	return record, true, nil
+}
+
+func (store *MemoryIdempotencyStore) Get(key string) (IdempotencyRecord, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if entry, exists := store.entries[key]; exists {
+		return entry.record, nil
+	}
+	return IdempotencyRecord{}, ErrIdempotencyKeyUnknown
+}
+
+func (store *MemoryIdempotencyStore) Complete(key string, response RecordedResponse) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	entry, exists := store.entries[key]
+	if !exists {
+		return ErrIdempotencyKeyUnknown
+	}
+	entry.record.Response = &response
+	return nil
+}
+
+func (store *MemoryIdempotencyStore) Release(key string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.entries, key)
+	return nil
+}
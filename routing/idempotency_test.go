@@ -0,0 +1,135 @@
+package routing
+
+import (
+	go_http "github.com/mwildt/go-http"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// createPayment counts its calls and waits for release, if not nil, before responding
+func createPayment(calls *atomic.Int32, release chan struct{}) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		count := calls.Add(1)
+		if release != nil {
+			<-release
+		}
+		body, _ := io.ReadAll(request.Body)
+		if string(body) == "fail" {
+			writer.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		writer.Header().Set("Location", "/payments/1")
+		writer.WriteHeader(http.StatusCreated)
+		writer.Write([]byte("payment " + string(rune('0'+count))))
+	}
+}
+
+// countingStore counts the requests that arrived at the store
+type countingStore struct {
+	IdempotencyStore
+	begins atomic.Int32
+}
+
+func (store *countingStore) Begin(key string, fingerprint string, now time.Time) (IdempotencyRecord, bool, error) {
+	store.begins.Add(1)
+	return store.IdempotencyStore.Begin(key, fingerprint, now)
+}
+
+func paymentRequest(key string, body string) *http.Request {
+	req := httptest.NewRequest("POST", "http://example.com/payments", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	return req
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	calls := &atomic.Int32{}
+	router := singleRoute(Post("/payments").Filter(Idempotency(IdempotencyConfig{})), createPayment(calls, nil))
+
+	first := serve(router, paymentRequest("k1", "amount=10"))
+	go_http.Assert(t, first.Code == 201 && first.Body.String() == "payment 1", "unexpected first response %d %s", first.Code, first.Body.String())
+	replay := serve(router, paymentRequest("k1", "amount=10"))
+	go_http.Assert(t, replay.Code == 201 && replay.Body.String() == "payment 1", "unexpected replay %d %s", replay.Code, replay.Body.String())
+	go_http.Assert(t, replay.Header().Get("Location") == "/payments/1", "headers not replayed")
+	go_http.Assert(t, replay.Header().Get("Idempotent-Replayed") == "true", "replay not marked")
+	go_http.Assert(t, calls.Load() == 1, "handler called %d times", calls.Load())
+
+	mismatch := serve(router, paymentRequest("k1", "amount=20"))
+	go_http.Assert(t, mismatch.Code == 422, "expected 422 for other payload, got %d", mismatch.Code)
+
+	serve(router, paymentRequest("k2", "amount=10"))
+	serve(router, paymentRequest("", "amount=10"))
+	go_http.Assert(t, calls.Load() == 3, "new and missing keys must reach the handler")
+}
+
+func TestIdempotencyServerErrorsAreNotRecorded(t *testing.T) {
+	calls := &atomic.Int32{}
+	router := singleRoute(Post("/payments").Filter(Idempotency(IdempotencyConfig{})), createPayment(calls, nil))
+
+	serve(router, paymentRequest("k1", "fail"))
+	recorder := serve(router, paymentRequest("k1", "fail"))
+	go_http.Assert(t, recorder.Code == 502 && calls.Load() == 2, "server error was replayed")
+}
+
+func TestIdempotencyConcurrentDuplicates(t *testing.T) {
+	for _, wait := range []time.Duration{0, 5 * time.Second} {
+		calls := &atomic.Int32{}
+		release := make(chan struct{})
+		store := &countingStore{IdempotencyStore: NewMemoryIdempotencyStore(time.Hour)}
+		router := singleRoute(Post("/payments").Filter(Idempotency(IdempotencyConfig{Wait: wait, Store: store})), createPayment(calls, release))
+
+		done := make(chan *httptest.ResponseRecorder)
+		go func() { done <- serve(router, paymentRequest("k1", "amount=10")) }()
+		for calls.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		if wait == 0 {
+			duplicate := serve(router, paymentRequest("k1", "amount=10"))
+			go_http.Assert(t, duplicate.Code == 409, "expected 409 for in-flight duplicate, got %d", duplicate.Code)
+			close(release)
+			<-done
+		} else {
+			waiting := make(chan *httptest.ResponseRecorder)
+			go func() { waiting <- serve(router, paymentRequest("k1", "amount=10")) }()
+			for store.begins.Load() < 2 {
+				time.Sleep(time.Millisecond)
+			}
+			close(release)
+			first, duplicate := <-done, <-waiting
+			go_http.Assert(t, first.Code == 201 && duplicate.Code == 201 && duplicate.Body.String() == first.Body.String(), "waiting duplicate not replayed %d", duplicate.Code)
+		}
+		go_http.Assert(t, calls.Load() == 1, "handler called %d times", calls.Load())
+	}
+}
+
+func TestIdempotencyKeyedByPrincipal(t *testing.T) {
+	calls := &atomic.Int32{}
+	userFilter := func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		next(w, r.WithContext(WithPrincipal(r.Context(), Principal{Name: r.Header.Get("X-User")})))
+	}
+	router := singleRoute(Post("/payments").Filter(userFilter).Filter(Idempotency(IdempotencyConfig{})), createPayment(calls, nil))
+	for _, user := range []string{"alice", "bob", "alice"} {
+		req := paymentRequest("k1", "")
+		req.Header.Set("X-User", user)
+		serve(router, req)
+	}
+	go_http.Assert(t, calls.Load() == 2, "keys must be scoped by principal, handler called %d times", calls.Load())
+}
+
+func TestIdempotencyKeyedByClientIPWithoutPrincipal(t *testing.T) {
+	calls := &atomic.Int32{}
+	router := singleRoute(Post("/payments").Filter(Idempotency(IdempotencyConfig{})), createPayment(calls, nil))
+	for _, remoteAddr := range []string{"10.0.0.1:1234", "10.0.0.2:1234", "10.0.0.1:5678"} {
+		req := paymentRequest("k1", "")
+		req.RemoteAddr = remoteAddr
+		serve(router, req)
+	}
+	go_http.Assert(t, calls.Load() == 2, "anonymous keys must be scoped by client ip, handler called %d times", calls.Load())
+}
@@ -0,0 +1,112 @@
+package routing
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mwildt/go-http/httputils"
+)
+
+type ipRules struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// IPAccessList allows addresses in the allow list which are not in the deny list. It fails closed: an empty
+// allow list denies everything, a deny-only list needs explicit "allow 0.0.0.0/0" and "allow ::/0" rules.
+type IPAccessList struct {
+	path  string
+	rules atomic.Pointer[ipRules]
+}
+
+func NewIPAccessList(allow []netip.Prefix, deny []netip.Prefix) *IPAccessList {
+	list := &IPAccessList{}
+	list.Update(allow, deny)
+	return list
+}
+
+// LoadIPAccessList reads lines of the form "allow 10.0.0.0/8" or "deny 192.0.2.1", "#" starts a comment
+func LoadIPAccessList(path string) (*IPAccessList, error) {
+	list := &IPAccessList{path: path}
+	if err := list.Reload(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (list *IPAccessList) Update(allow []netip.Prefix, deny []netip.Prefix) {
+	list.rules.Store(&ipRules{allow: allow, deny: deny})
+}
+
+// Reload replaces the rules by the content of the file, the current rules are kept on error
+func (list *IPAccessList) Reload() error {
+	if list.path == "" {
+		return fmt.Errorf("ip access list has no file")
+	}
+	file, err := os.Open(list.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rules := &ipRules{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		} else if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: expected '<allow|deny> <ip or cidr>'", list.path, lineNumber)
+		}
+		prefixes, err := ParsePrefixes(fields[1])
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", list.path, lineNumber, err)
+		}
+		switch fields[0] {
+		case "allow":
+			rules.allow = append(rules.allow, prefixes...)
+		case "deny":
+			rules.deny = append(rules.deny, prefixes...)
+		default:
+			return fmt.Errorf("%s:%d: unknown rule '%s'", list.path, lineNumber, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	list.rules.Store(rules)
+	return nil
+}
+
+func (list *IPAccessList) Allows(ip netip.Addr) bool {
+	rules := list.rules.Load()
+	if rules == nil {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, prefix := range rules.deny {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	for _, prefix := range rules.allow {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (list *IPAccessList) Filter(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if ip, ok := remoteIP(r); !ok || !list.Allows(ip) {
+		httputils.Forbidden(w, r)
+		return
+	}
+	next(w, r)
+}
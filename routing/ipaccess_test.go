@@ -0,0 +1,80 @@
+package routing
+
+import (
+	go_http "github.com/mwildt/go-http"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIPAccessList(t *testing.T) {
+	allow, _ := ParsePrefixes("10.0.0.0/8", "2001:db8::/32")
+	deny, _ := ParsePrefixes("10.0.0.13", "2001:db8:bad::/48")
+	list := NewIPAccessList(allow, deny)
+
+	for ip, expected := range map[string]bool{
+		"10.1.2.3":          true,
+		"10.0.0.13":         false,
+		"192.0.2.1":         false,
+		"::ffff:10.1.2.3":   true,
+		"2001:db8::1":       true,
+		"2001:db8:bad::1":   false,
+		"2001:db9::1":       false,
+		"2001:db8:bad:1::1": false,
+	} {
+		go_http.Assert(t, list.Allows(netip.MustParseAddr(ip)) == expected, "unexpected result for %s", ip)
+	}
+
+	list.Update(nil, deny)
+	go_http.Assert(t, !list.Allows(netip.MustParseAddr("192.0.2.1")), "empty allow list must deny all")
+	everything, _ := ParsePrefixes("0.0.0.0/0", "::/0")
+	list.Update(everything, deny)
+	go_http.Assert(t, list.Allows(netip.MustParseAddr("192.0.2.1")) && !list.Allows(netip.MustParseAddr("10.0.0.13")), "explicit allow all not applied")
+}
+
+func TestIPAccessListFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "admin.acl")
+	os.WriteFile(path, []byte("# admin network\nallow 192.0.2.0/24\ndeny 192.0.2.66 # compromised\n"), 0600)
+	list, err := LoadIPAccessList(path)
+	go_http.AssertNoError(t, err, "load failed")
+
+	router := NewRouter(func(router Routing) {
+		router.Route(Path("/admin").Filter(list.Filter), func(router Routing) {
+			router.HandleFunc(Get("/status"), func(writer http.ResponseWriter, request *http.Request) {
+				writer.Write([]byte("OK"))
+			})
+		})
+	})
+	serve := func(remoteAddr string) int {
+		req := httptest.NewRequest("GET", "http://example.com/admin/status", nil)
+		req.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	go_http.Assert(t, serve("192.0.2.1:1234") == 200, "allowed address rejected")
+	go_http.Assert(t, serve("192.0.2.66:1234") == 403, "denied address accepted")
+	go_http.Assert(t, serve("198.51.100.1:1234") == 403, "unknown address accepted")
+
+	os.WriteFile(path, []byte("allow 198.51.100.0/24\n"), 0600)
+	go_http.AssertNoError(t, list.Reload(), "reload failed")
+	go_http.Assert(t, serve("198.51.100.1:1234") == 200, "reloaded list not used")
+
+	os.WriteFile(path, []byte("permit 192.0.2.1\n"), 0600)
+	go_http.Assert(t, list.Reload() != nil, "invalid file accepted")
+	go_http.Assert(t, serve("198.51.100.1:1234") == 200, "rules must be kept on reload error")
+
+	os.WriteFile(path, []byte("deny 192.0.2.66\n"), 0600)
+	go_http.AssertNoError(t, list.Reload(), "reload failed")
+	go_http.Assert(t, serve("198.51.100.1:1234") == 403, "deny-only list must fail closed")
+}
+
+func TestLoadIPAccessListError(t *testing.T) {
+	list, err := LoadIPAccessList(filepath.Join(t.TempDir(), "missing.acl"))
+	go_http.Assert(t, list == nil && err != nil, "missing file must fail")
+	go_http.Assert(t, !(&IPAccessList{}).Allows(netip.MustParseAddr("192.0.2.1")), "list without rules must deny")
+}
@@ -0,0 +1,298 @@
+package routing
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mwildt/go-http/httputils"
+)
+
+const contextClaims = contextKey("router.http.claims")
+
+type JWTClaims map[string]any
+
+func (claims JWTClaims) String(name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// Strings returns a claim which is either a single string, a space separated string or an array of strings
+func (claims JWTClaims) Strings(name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return strings.Fields(value)
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// time returns a NumericDate claim, present claims of another type are malformed
+func (claims JWTClaims) time(name string) (time.Time, bool, error) {
+	raw, present := claims[name]
+	if !present {
+		return time.Time{}, false, nil
+	}
+	value, ok := raw.(float64)
+	if !ok {
+		return time.Time{}, true, ErrTokenMalformed
+	}
+	return time.Unix(int64(value), 0), true, nil
+}
+
+func (claims JWTClaims) scopes() []string {
+	if scopes := claims.Strings("scope"); scopes != nil {
+		return scopes
+	}
+	return claims.Strings("scp")
+}
+
+func WithClaims(c context.Context, claims JWTClaims) context.Context {
+	return context.WithValue(c, contextClaims, claims)
+}
+
+func Claims(c context.Context) (JWTClaims, bool) {
+	claims, ok := c.Value(contextClaims).(JWTClaims)
+	return claims, ok
+}
+
+// JWTKey holds a []byte secret for HS256, an *rsa.PublicKey for RS256 or an *ecdsa.PublicKey for ES256
+type JWTKey struct {
+	ID        string
+	Algorithm string
+	Key       any
+}
+
+type JWTKeySet []JWTKey
+
+func LoadJWKS(path string) (JWTKeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+func ParseJWKS(data []byte) (JWTKeySet, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(JWTKeySet, 0, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key '%s': %w", jwk.Kid, err)
+		}
+		keys = append(keys, JWTKey{ID: jwk.Kid, Algorithm: jwk.Alg, Key: key})
+	}
+	return keys, nil
+}
+
+func (jwk jsonWebKey) publicKey() (any, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch jwk.Kty {
+	case "oct":
+		return decode(jwk.K)
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve '%s'", jwk.Crv)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s'", jwk.Kty)
+	}
+}
+
+type JWTConfig struct {
+	Keys      JWTKeySet
+	Issuer    string
+	Audience  string
+	ClockSkew time.Duration
+	// Scopes are required in the scope or scp claim, missing scopes are answered with 403
+	Scopes []string
+	Realm  string
+}
+
+var (
+	ErrTokenMalformed = errors.New("malformed token")
+	ErrTokenSignature = errors.New("invalid signature")
+	ErrTokenExpired   = errors.New("token expired")
+	ErrTokenNotYet    = errors.New("token not valid yet")
+	ErrTokenIssuer    = errors.New("invalid issuer")
+	ErrTokenAudience  = errors.New("invalid audience")
+)
+
+func VerifyJWT(token string, config JWTConfig, now time.Time) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if data, err := base64.RawURLEncoding.DecodeString(parts[0]); err != nil {
+		return nil, ErrTokenMalformed
+	} else if err := json.Unmarshal(data, &header); err != nil {
+		return nil, ErrTokenMalformed
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	if !config.Keys.verify(header.Alg, header.Kid, parts[0]+"."+parts[1], signature) {
+		return nil, ErrTokenSignature
+	}
+
+	claims := JWTClaims{}
+	if data, err := base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+		return nil, ErrTokenMalformed
+	} else if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, ErrTokenMalformed
+	}
+	exp, hasExp, err := claims.time("exp")
+	if err != nil {
+		return nil, err
+	} else if hasExp && now.After(exp.Add(config.ClockSkew)) {
+		return nil, ErrTokenExpired
+	}
+	nbf, hasNbf, err := claims.time("nbf")
+	if err != nil {
+		return nil, err
+	} else if hasNbf && now.Add(config.ClockSkew).Before(nbf) {
+		return nil, ErrTokenNotYet
+	}
+	if config.Issuer != "" && claims.String("iss") != config.Issuer {
+		return nil, ErrTokenIssuer
+	}
+	if config.Audience != "" && !slices.Contains(claims.Strings("aud"), config.Audience) {
+		return nil, ErrTokenAudience
+	}
+	return claims, nil
+}
+
+func (keys JWTKeySet) verify(algorithm string, keyID string, signed string, signature []byte) bool {
+	digest := sha256.Sum256([]byte(signed))
+	for _, key := range keys {
+		if (keyID != "" && key.ID != "" && key.ID != keyID) || (key.Algorithm != "" && key.Algorithm != algorithm) {
+			continue
+		}
+		switch k := key.Key.(type) {
+		case []byte:
+			if algorithm == "HS256" {
+				mac := hmac.New(sha256.New, k)
+				mac.Write([]byte(signed))
+				if hmac.Equal(mac.Sum(nil), signature) {
+					return true
+				}
+			}
+		case *rsa.PublicKey:
+			if algorithm == "RS256" && rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil {
+				return true
+			}
+		case *ecdsa.PublicKey:
+			if algorithm == "ES256" && len(signature) == 64 {
+				r := new(big.Int).SetBytes(signature[:32])
+				s := new(big.Int).SetBytes(signature[32:])
+				if ecdsa.Verify(k, digest[:], r, s) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func BearerJWT(config JWTConfig) Filter {
+	if config.Realm == "" {
+		config.Realm = "api"
+	}
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || token == "" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", config.Realm))
+			httputils.Unauthorized(w, r)
+			return
+		}
+		claims, err := VerifyJWT(strings.TrimSpace(token), config, time.Now())
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q, error=\"invalid_token\", error_description=%q", config.Realm, err.Error()))
+			httputils.Unauthorized(w, r)
+			return
+		}
+		scopes := claims.scopes()
+		for _, required := range config.Scopes {
+			if !slices.Contains(scopes, required) {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q, error=\"insufficient_scope\", scope=%q", config.Realm, strings.Join(config.Scopes, " ")))
+				httputils.Forbidden(w, r)
+				return
+			}
+		}
+		principal := Principal{Name: claims.String("sub"), Roles: claims.Strings("roles")}
+		ctx := WithScopes(WithPrincipal(WithClaims(r.Context(), claims), principal), scopes)
+		next(w, r.WithContext(ctx))
+	}
+}
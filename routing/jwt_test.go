@@ -0,0 +1,165 @@
+package routing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	go_http "github.com/mwildt/go-http"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func signJWT(t *testing.T, algorithm string, keyID string, key any, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": algorithm, "typ": "JWT", "kid": keyID})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		signature, _ = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		go_http.AssertNoError(t, err, "signing failed")
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifyJWT(t *testing.T) {
+	secret := []byte("hmac-secret")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	config := JWTConfig{
+		Keys: JWTKeySet{
+			{ID: "hs", Key: secret},
+			{ID: "rs", Algorithm: "RS256", Key: &rsaKey.PublicKey},
+			{ID: "es", Key: &ecKey.PublicKey},
+		},
+		Issuer:    "https://issuer.example.com",
+		Audience:  "api",
+		ClockSkew: time.Minute,
+	}
+	now := time.Now()
+	claims := func(overrides map[string]any) map[string]any {
+		base := map[string]any{"sub": "alice", "iss": config.Issuer, "aud": []string{"other", "api"}, "exp": now.Add(time.Hour).Unix()}
+		for key, value := range overrides {
+			base[key] = value
+		}
+		return base
+	}
+
+	for name, token := range map[string]string{
+		"HS256": signJWT(t, "HS256", "hs", secret, claims(nil)),
+		"RS256": signJWT(t, "RS256", "rs", rsaKey, claims(nil)),
+		"ES256": signJWT(t, "ES256", "es", ecKey, claims(map[string]any{"aud": "api"})),
+		"skew":  signJWT(t, "HS256", "hs", secret, claims(map[string]any{"exp": now.Add(-30 * time.Second).Unix()})),
+	} {
+		verified, err := VerifyJWT(token, config, now)
+		go_http.AssertNoError(t, err, "valid %s token rejected", name)
+		go_http.Assert(t, verified.String("sub") == "alice", "unexpected subject for %s", name)
+	}
+
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	for expected, token := range map[error]string{
+		ErrTokenMalformed: "not.a-token",
+		ErrTokenSignature: signJWT(t, "ES256", "es", otherKey, claims(nil)),
+		ErrTokenExpired:   signJWT(t, "HS256", "hs", secret, claims(map[string]any{"exp": now.Add(-2 * time.Minute).Unix()})),
+		ErrTokenNotYet:    signJWT(t, "HS256", "hs", secret, claims(map[string]any{"nbf": now.Add(2 * time.Minute).Unix()})),
+		ErrTokenIssuer:    signJWT(t, "HS256", "hs", secret, claims(map[string]any{"iss": "https://evil.com"})),
+		ErrTokenAudience:  signJWT(t, "HS256", "hs", secret, claims(map[string]any{"aud": "other"})),
+	} {
+		_, err := VerifyJWT(token, config, now)
+		go_http.Assert(t, err == expected, "expected %v, got %v", expected, err)
+	}
+
+	for _, claim := range []string{"exp", "nbf"} {
+		token := signJWT(t, "HS256", "hs", secret, claims(map[string]any{claim: strconv.FormatInt(now.Unix(), 10)}))
+		_, err := VerifyJWT(token, config, now)
+		go_http.Assert(t, err == ErrTokenMalformed, "string %s accepted: %v", claim, err)
+	}
+
+	// the algorithm must match the key type, so an rsa public key can not be used as hmac secret
+	publicKeyBytes := rsaKey.PublicKey.N.Bytes()
+	_, err := VerifyJWT(signJWT(t, "HS256", "rs", publicKeyBytes, claims(nil)), config, now)
+	go_http.Assert(t, err == ErrTokenSignature, "algorithm confusion not prevented")
+	unsigned := strings.Split(signJWT(t, "none", "hs", secret, claims(nil)), ".")
+	_, err = VerifyJWT(unsigned[0]+"."+unsigned[1]+".", config, now)
+	go_http.Assert(t, err != nil, "alg none accepted")
+}
+
+func TestLoadJWKS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+	jwks, _ := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rs", "alg": "RS256", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+		{"kty": "EC", "kid": "es", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": encode(rsaKey.N), "e": "AQAB"},
+	}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	os.WriteFile(path, jwks, 0600)
+
+	keys, err := LoadJWKS(path)
+	go_http.AssertNoError(t, err, "loading jwks failed")
+	go_http.Assert(t, len(keys) == 2, "unexpected number of keys %d", len(keys))
+
+	config := JWTConfig{Keys: keys}
+	_, err = VerifyJWT(signJWT(t, "RS256", "rs", rsaKey, map[string]any{"sub": "a"}), config, time.Now())
+	go_http.AssertNoError(t, err, "rsa token rejected")
+	_, err = VerifyJWT(signJWT(t, "ES256", "es", ecKey, map[string]any{"sub": "a"}), config, time.Now())
+	go_http.AssertNoError(t, err, "ec token rejected")
+}
+
+func TestBearerJWTFilter(t *testing.T) {
+	secret := []byte("hmac-secret")
+	router := NewRouter(func(router Routing) {
+		filter := BearerJWT(JWTConfig{Keys: JWTKeySet{{Key: secret}}, Scopes: []string{"orders:read"}, Realm: "orders"})
+		router.HandleFunc(Get("/orders").Filter(filter), func(writer http.ResponseWriter, request *http.Request) {
+			claims, _ := Claims(request.Context())
+			principal, _ := GetPrincipal(request.Context())
+			writer.Write([]byte(claims.String("sub") + ":" + strings.Join(principal.Roles, ",")))
+		})
+	})
+	serve := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "http://example.com/orders", nil)
+		req.Header.Set("Authorization", authorization)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := serve("Bearer " + signJWT(t, "HS256", "", secret, map[string]any{"sub": "alice", "scope": "orders:read orders:write", "roles": []string{"admin"}}))
+	go_http.Assert(t, recorder.Code == 200, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, recorder.Body.String() == "alice:admin", "unexpected response body %s", recorder.Body.String())
+
+	recorder = serve("")
+	go_http.Assert(t, recorder.Code == 401, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, recorder.Header().Get("WWW-Authenticate") == `Bearer realm="orders"`, "unexpected challenge %s", recorder.Header().Get("WWW-Authenticate"))
+
+	recorder = serve("Bearer " + signJWT(t, "HS256", "", []byte("wrong"), map[string]any{"sub": "alice"}))
+	go_http.Assert(t, recorder.Code == 401, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, strings.Contains(recorder.Header().Get("WWW-Authenticate"), `error="invalid_token"`), "unexpected challenge %s", recorder.Header().Get("WWW-Authenticate"))
+
+	recorder = serve("Bearer " + signJWT(t, "HS256", "", secret, map[string]any{"sub": "alice", "scp": []string{"orders:write"}}))
+	go_http.Assert(t, recorder.Code == 403, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, strings.Contains(recorder.Header().Get("WWW-Authenticate"), `error="insufficient_scope", scope="orders:read"`), "unexpected challenge %s", recorder.Header().Get("WWW-Authenticate"))
+}
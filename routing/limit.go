@@ -0,0 +1,18 @@
+package routing
+
+import (
+	"net/http"
+
+	"github.com/mwildt/go-http/httputils"
+)
+
+func LimitBody(size int64) Filter {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if r.ContentLength > size {
+			httputils.Send(w, r, http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, size)
+		next(w, r)
+	}
+}
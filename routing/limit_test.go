@@ -0,0 +1,58 @@
+package routing
+
+import (
+	"errors"
+	go_http "github.com/mwildt/go-http"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func readBody(writer http.ResponseWriter, request *http.Request) {
+	body, err := io.ReadAll(request.Body)
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		writer.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+	writer.Write(body)
+}
+
+func TestLimitBody(t *testing.T) {
+	router := NewRouter(func(router Routing) {
+		router.HandleFunc(Post("/data").Filter(LimitBody(4)), readBody)
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "http://example.com/data", strings.NewReader("1234")))
+	go_http.Assert(t, recorder.Code == 200, "unexpected response status %d", recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "http://example.com/data", strings.NewReader("12345")))
+	go_http.Assert(t, recorder.Code == 413, "unexpected response status %d", recorder.Code)
+
+	// without content length the limit is enforced while reading
+	req := httptest.NewRequest("POST", "http://example.com/data", io.MultiReader(strings.NewReader("12345")))
+	req.ContentLength = -1
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	go_http.Assert(t, recorder.Code == 413, "unexpected response status %d", recorder.Code)
+}
+
+func TestMaxBodySizeInheritance(t *testing.T) {
+	router := NewRouter(func(router Routing) {
+		router.Route(Path("/api").MaxBodySize(4), func(router Routing) {
+			router.HandleFunc(Post("/json"), readBody)
+			router.HandleFunc(Post("/upload").MaxBodySize(10), readBody)
+			router.HandleFunc(Post("/unlimited").MaxBodySize(-1), readBody)
+		})
+	})
+
+	for path, expected := range map[string]int{"/api/json": 413, "/api/upload": 200, "/api/unlimited": 200} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("POST", "http://example.com"+path, strings.NewReader("123456")))
+		go_http.Assert(t, recorder.Code == expected, "unexpected response status %d for %s", recorder.Code, path)
+	}
+}
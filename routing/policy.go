@@ -0,0 +1,109 @@
+package routing
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/mwildt/go-http/httputils"
+)
+
+type Policy struct {
+	description string
+	allows      func(request *http.Request, principal Principal) bool
+}
+
+func (policy Policy) String() string {
+	return policy.description
+}
+
+func HasRole(role string) Policy {
+	return Policy{
+		description: "hasRole(" + role + ")",
+		allows: func(request *http.Request, principal Principal) bool {
+			return slices.Contains(principal.Roles, role)
+		},
+	}
+}
+
+func HasScope(scope string) Policy {
+	return Policy{
+		description: "hasScope(" + scope + ")",
+		allows: func(request *http.Request, principal Principal) bool {
+			return slices.Contains(Scopes(request.Context()), scope)
+		},
+	}
+}
+
+func Authenticated() Policy {
+	return Policy{
+		description: "authenticated",
+		allows: func(request *http.Request, principal Principal) bool {
+			return true
+		},
+	}
+}
+
+// Predicate is a custom policy, the description is used for route introspection
+func Predicate(description string, predicate func(principal Principal, params Parameters) bool) Policy {
+	return Policy{
+		description: description,
+		allows: func(request *http.Request, principal Principal) bool {
+			return predicate(principal, GetParameters(request.Context()))
+		},
+	}
+}
+
+func AllOf(policies ...Policy) Policy {
+	if len(policies) == 1 {
+		return policies[0]
+	}
+	return Policy{
+		description: describePolicies("allOf", policies),
+		allows: func(request *http.Request, principal Principal) bool {
+			for _, policy := range policies {
+				if !policy.allows(request, principal) {
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
+
+func AnyOf(policies ...Policy) Policy {
+	if len(policies) == 1 {
+		return policies[0]
+	}
+	return Policy{
+		description: describePolicies("anyOf", policies),
+		allows: func(request *http.Request, principal Principal) bool {
+			for _, policy := range policies {
+				if policy.allows(request, principal) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+func describePolicies(name string, policies []Policy) string {
+	descriptions := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		descriptions = append(descriptions, policy.description)
+	}
+	return name + "(" + strings.Join(descriptions, ", ") + ")"
+}
+
+// Filter answers 401 without authenticated principal and 403 if the policy denies the request
+func (policy Policy) Filter(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	principal, authenticated := GetPrincipal(r.Context())
+	if !authenticated {
+		httputils.Unauthorized(w, r)
+	} else if !policy.allows(r, principal) {
+		httputils.Forbidden(w, r)
+	} else {
+		next(w, r)
+	}
+}
@@ -0,0 +1,83 @@
+package routing
+
+import (
+	go_http "github.com/mwildt/go-http"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// headerAuth authenticates the user of the X-User header with the roles of X-Roles
+func headerAuth(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if user := r.Header.Get("X-User"); user != "" {
+		principal := Principal{Name: user, Roles: strings.Split(r.Header.Get("X-Roles"), ",")}
+		r = r.WithContext(WithScopes(WithPrincipal(r.Context(), principal), strings.Split(r.Header.Get("X-Scopes"), ",")))
+	}
+	next(w, r)
+}
+
+func policyRouter() *Router {
+	ownAccount := Predicate("ownAccount", func(principal Principal, params Parameters) bool {
+		return principal.Name == params["user"]
+	})
+	ok := func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte("OK"))
+	}
+	return NewRouter(func(router Routing) {
+		router.HandleFunc(Get("/public"), ok)
+		router.Route(Path("/admin").Require(HasRole("admin")).Filter(headerAuth), func(router Routing) {
+			router.HandleFunc(Get("/status"), ok)
+			router.HandleFunc(Delete("/users/{id}").Require(HasScope("users:delete")), ok)
+		})
+		router.HandleFunc(Get("/accounts/{user}").Filter(headerAuth).Require(AnyOf(HasRole("admin"), ownAccount)), ok)
+	})
+}
+
+func servePolicy(router *Router, method string, path string, user string, roles string, scopes string) int {
+	req := httptest.NewRequest(method, "http://example.com"+path, nil)
+	req.Header.Set("X-User", user)
+	req.Header.Set("X-Roles", roles)
+	req.Header.Set("X-Scopes", scopes)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func TestRequirePolicies(t *testing.T) {
+	router := policyRouter()
+	for _, test := range []struct {
+		method, path, user, roles, scopes string
+		expected                          int
+	}{
+		{"GET", "/public", "", "", "", 200},
+		{"GET", "/admin/status", "", "", "", 401},
+		{"GET", "/admin/status", "bob", "user", "", 403},
+		{"GET", "/admin/status", "alice", "user,admin", "", 200},
+		{"DELETE", "/admin/users/1", "alice", "admin", "users:read", 403},
+		{"DELETE", "/admin/users/1", "alice", "admin", "users:delete", 200},
+		{"DELETE", "/admin/users/1", "bob", "user", "users:delete", 403},
+		{"GET", "/accounts/bob", "bob", "user", "", 200},
+		{"GET", "/accounts/alice", "bob", "user", "", 403},
+		{"GET", "/accounts/bob", "alice", "admin", "", 200},
+	} {
+		code := servePolicy(router, test.method, test.path, test.user, test.roles, test.scopes)
+		go_http.Assert(t, code == test.expected, "unexpected response status %d for %s %s as %s", code, test.method, test.path, test.user)
+	}
+}
+
+func TestRouteIntrospection(t *testing.T) {
+	routes := policyRouter().Routes()
+	go_http.Assert(t, len(routes) == 4, "unexpected number of routes %d", len(routes))
+
+	expected := map[string]string{
+		"/public":           "",
+		"/admin/status":     "hasRole(admin)",
+		"/admin/users/{id}": "allOf(hasRole(admin), hasScope(users:delete))",
+		"/accounts/{user}":  "anyOf(hasRole(admin), ownAccount)",
+	}
+	for _, route := range routes {
+		go_http.Assert(t, route.Policy == expected[route.Path], "unexpected policy '%s' for %s", route.Policy, route.Path)
+	}
+	go_http.Assert(t, routes[2].Methods[0] == "DELETE", "unexpected methods %v", routes[2].Methods)
+}
@@ -0,0 +1,34 @@
+package routing
+
+import "context"
+
+const (
+	contextPrincipal = contextKey("router.http.principal")
+	contextScopes    = contextKey("router.http.scopes")
+)
+
+type Principal struct {
+	Name  string
+	Roles []string
+}
+
+func WithPrincipal(c context.Context, principal Principal) context.Context {
+	return context.WithValue(c, contextPrincipal, principal)
+}
+
+func GetPrincipal(c context.Context) (Principal, bool) {
+	principal, ok := c.Value(contextPrincipal).(Principal)
+	return principal, ok
+}
+
+func WithScopes(c context.Context, scopes []string) context.Context {
+	return context.WithValue(c, contextScopes, scopes)
+}
+
+func Scopes(c context.Context) []string {
+	if value := c.Value(contextScopes); value != nil {
+		return value.([]string)
+	} else {
+		return make([]string, 0)
+	}
+}
@@ -0,0 +1,157 @@
+package routing
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mwildt/go-http/httputils"
+)
+
+type KeyFunc func(r *http.Request) string
+
+func KeyByIP() KeyFunc {
+	return func(r *http.Request) string {
+		if ip, ok := remoteIP(r); ok {
+			return "ip:" + ip.String()
+		}
+		return "ip:" + r.RemoteAddr
+	}
+}
+
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return "header:" + name + ":" + r.Header.Get(name)
+	}
+}
+
+func KeyByParameter(name string) KeyFunc {
+	return func(r *http.Request) string {
+		value, _ := GetParameter(r.Context(), name)
+		return "param:" + name + ":" + value
+	}
+}
+
+type RateLimit struct {
+	// Requests per Period refill the bucket, Burst is its capacity (defaults to Requests)
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token is available, if the request was not allowed
+	RetryAfter time.Duration
+}
+
+type RateLimitStore interface {
+	Take(key string, limit RateLimit, now time.Time) RateLimitResult
+}
+
+type RateLimitConfig struct {
+	Limit RateLimit
+	Key   KeyFunc
+	Store RateLimitStore
+}
+
+// RateLimiting panics if the limit does not allow any requests, as the rate would be undefined
+func RateLimiting(config RateLimitConfig) Filter {
+	if config.Limit.Requests <= 0 || config.Limit.Period <= 0 {
+		panic("routing: rate limit requires positive Requests and Period")
+	}
+	if config.Limit.Burst <= 0 {
+		config.Limit.Burst = config.Limit.Requests
+	}
+	if config.Key == nil {
+		config.Key = KeyByIP()
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryRateLimitStore(10 * time.Minute)
+	}
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		result := config.Store.Take(config.Key(r), config.Limit, time.Now())
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(config.Limit.Burst))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(result.RetryAfter))))
+			httputils.Send(w, r, http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time
+}
+
+type MemoryRateLimitStore struct {
+	mutex       sync.Mutex
+	buckets     map[string]*tokenBucket
+	idleTimeout time.Duration
+	lastSweep   time.Time
+}
+
+// NewMemoryRateLimitStore keeps token buckets in memory, buckets which are full and idle for
+// idleTimeout are evicted, as a full bucket is equivalent to an unknown key.
+func NewMemoryRateLimitStore(idleTimeout time.Duration) *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket), idleTimeout: idleTimeout}
+}
+
+func (store *MemoryRateLimitStore) Take(key string, limit RateLimit, now time.Time) RateLimitResult {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if now.Sub(store.lastSweep) > store.idleTimeout {
+		store.sweep(now)
+	}
+
+	capacity := float64(max(limit.Burst, 1))
+	rate := float64(limit.Requests) / limit.Period.Seconds()
+	bucket, exists := store.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: capacity, last: now}
+		store.buckets[key] = bucket
+	} else if elapsed := now.Sub(bucket.last).Seconds(); elapsed > 0 {
+		bucket.tokens = math.Min(capacity, bucket.tokens+elapsed*rate)
+		bucket.last = now
+	}
+
+	result := RateLimitResult{}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsDuration((1 - bucket.tokens) / rate)
+	}
+	result.Remaining = int(bucket.tokens)
+	result.Reset = secondsDuration((capacity - bucket.tokens) / rate)
+	bucket.full = now.Add(result.Reset)
+	return result
+}
+
+func (store *MemoryRateLimitStore) sweep(now time.Time) {
+	store.lastSweep = now
+	for key, bucket := range store.buckets {
+		if now.Sub(bucket.full) > store.idleTimeout {
+			delete(store.buckets, key)
+		}
+	}
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,83 @@
+package routing
+
+import (
+	go_http "github.com/mwildt/go-http"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	store := NewMemoryRateLimitStore(time.Minute)
+	limit := RateLimit{Requests: 1, Period: time.Second, Burst: 2}
+	now := time.Now()
+
+	result := store.Take("a", limit, now)
+	go_http.Assert(t, result.Allowed && result.Remaining == 1, "unexpected result %v", result)
+	result = store.Take("a", limit, now)
+	go_http.Assert(t, result.Allowed && result.Remaining == 0, "unexpected result %v", result)
+	result = store.Take("a", limit, now)
+	go_http.Assert(t, !result.Allowed && result.RetryAfter == time.Second, "unexpected result %v", result)
+	go_http.Assert(t, result.Reset == 2*time.Second, "unexpected reset %v", result.Reset)
+
+	result = store.Take("b", limit, now)
+	go_http.Assert(t, result.Allowed, "keys must have separate buckets")
+
+	result = store.Take("a", limit, now.Add(1500*time.Millisecond))
+	go_http.Assert(t, result.Allowed && result.Remaining == 0, "bucket not refilled %v", result)
+}
+
+func TestRateLimitEviction(t *testing.T) {
+	store := NewMemoryRateLimitStore(time.Minute)
+	limit := RateLimit{Requests: 10, Period: time.Second}
+	now := time.Now()
+	store.Take("a", limit, now)
+	store.Take("b", limit, now.Add(2*time.Minute))
+	go_http.Assert(t, len(store.buckets) == 1, "idle bucket not evicted, size %d", len(store.buckets))
+}
+
+func TestRateLimitingFilter(t *testing.T) {
+	filter := RateLimiting(RateLimitConfig{
+		Limit: RateLimit{Requests: 2, Period: time.Minute},
+		Key:   KeyByParameter("tenant"),
+	})
+	router := singleRoute(Get("/tenants/{tenant}").Filter(filter), func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte("OK"))
+	})
+	serveTenant := func(tenant string) *httptest.ResponseRecorder {
+		return serve(router, httptest.NewRequest("GET", "http://example.com/tenants/"+tenant, nil))
+	}
+
+	serveTenant("a")
+	recorder := serveTenant("a")
+	go_http.Assert(t, recorder.Code == 200, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, recorder.Header().Get("RateLimit-Limit") == "2", "unexpected limit header %s", recorder.Header().Get("RateLimit-Limit"))
+	go_http.Assert(t, recorder.Header().Get("RateLimit-Remaining") == "0", "unexpected remaining header %s", recorder.Header().Get("RateLimit-Remaining"))
+
+	recorder = serveTenant("a")
+	go_http.Assert(t, recorder.Code == 429, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, recorder.Header().Get("Retry-After") == "30", "unexpected retry after %s", recorder.Header().Get("Retry-After"))
+
+	recorder = serveTenant("b")
+	go_http.Assert(t, recorder.Code == 200, "unexpected response status %d", recorder.Code)
+}
+
+func TestRateLimitingKeys(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://example.com/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("X-API-Key", "secret")
+	go_http.Assert(t, KeyByIP()(req) == "ip:192.0.2.1", "unexpected ip key %s", KeyByIP()(req))
+	go_http.Assert(t, KeyByHeader("X-API-Key")(req) == "header:X-API-Key:secret", "unexpected header key %s", KeyByHeader("X-API-Key")(req))
+}
+
+func TestRateLimitingInvalidLimit(t *testing.T) {
+	for _, limit := range []RateLimit{{Requests: 0, Period: time.Minute}, {Requests: 1}} {
+		func() {
+			defer func() {
+				go_http.Assert(t, recover() != nil, "invalid limit %v must be rejected", limit)
+			}()
+			RateLimiting(RateLimitConfig{Limit: limit})
+		}()
+	}
+}
@@ -0,0 +1,138 @@
+package routing
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+const contextClientIP = contextKey("router.http.clientIp")
+
+type RealIPConfig struct {
+	// forwarding headers are only evaluated for peers and hops within TrustedProxies
+	TrustedProxies    []netip.Prefix
+	RewriteRemoteAddr bool
+}
+
+func ParsePrefixes(values ...string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func WithClientIP(c context.Context, ip netip.Addr) context.Context {
+	return context.WithValue(c, contextClientIP, ip)
+}
+
+func ClientIP(c context.Context) (netip.Addr, bool) {
+	ip, ok := c.Value(contextClientIP).(netip.Addr)
+	return ip, ok
+}
+
+// remoteIP is the resolved client ip, or the peer address if RealIP is not used
+func remoteIP(r *http.Request) (netip.Addr, bool) {
+	if ip, ok := ClientIP(r.Context()); ok {
+		return ip, true
+	}
+	return parseIP(r.RemoteAddr)
+}
+
+func RealIP(config RealIPConfig) Filter {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		peer, ok := parseIP(r.RemoteAddr)
+		if !ok {
+			next(w, r)
+			return
+		}
+		client := config.resolve(peer, r.Header)
+		if config.RewriteRemoteAddr {
+			r.RemoteAddr = client.String()
+		}
+		next(w, r.WithContext(WithClientIP(r.Context(), client)))
+	}
+}
+
+func (config RealIPConfig) trusted(ip netip.Addr) bool {
+	for _, prefix := range config.TrustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (config RealIPConfig) resolve(peer netip.Addr, header http.Header) netip.Addr {
+	if !config.trusted(peer) {
+		return peer
+	}
+	var hops []string
+	if forwarded := header.Values("Forwarded"); len(forwarded) > 0 {
+		hops = parseForwardedFor(forwarded)
+	} else if forwardedFor := header.Values("X-Forwarded-For"); len(forwardedFor) > 0 {
+		for _, value := range forwardedFor {
+			hops = append(hops, strings.Split(value, ",")...)
+		}
+	} else if realIP, ok := parseIP(header.Get("X-Real-IP")); ok {
+		return realIP
+	}
+
+	// walk from the nearest hop to the client and stop at the first untrusted address
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseIP(hops[i])
+		if !ok {
+			break
+		}
+		client = hop
+		if !config.trusted(hop) {
+			break
+		}
+	}
+	return client
+}
+
+func parseForwardedFor(values []string) (hops []string) {
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			hop := ""
+			for _, pair := range strings.Split(element, ";") {
+				key, val, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				if strings.EqualFold(key, "for") {
+					hop = strings.Trim(val, "\"")
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// parseIP accepts addresses with or without port, IPv6 addresses optionally in brackets
+func parseIP(value string) (netip.Addr, bool) {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
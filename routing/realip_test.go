@@ -0,0 +1,61 @@
+package routing
+
+import (
+	go_http "github.com/mwildt/go-http"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func realIPRouter(t *testing.T, rewrite bool) *Router {
+	trusted, err := ParsePrefixes("10.0.0.0/8", "2001:db8::/32", "192.0.2.1")
+	go_http.AssertNoError(t, err, "invalid prefixes")
+	return NewRouter(func(router Routing) {
+		router.HandleFunc(Path("/ip").Filter(RealIP(RealIPConfig{TrustedProxies: trusted, RewriteRemoteAddr: rewrite})), func(writer http.ResponseWriter, request *http.Request) {
+			ip, _ := ClientIP(request.Context())
+			writer.Write([]byte(ip.String() + " " + request.RemoteAddr))
+		})
+	})
+}
+
+func TestRealIP(t *testing.T) {
+	router := realIPRouter(t, false)
+	for _, test := range []struct {
+		remoteAddr, header, value, expected string
+	}{
+		{"203.0.113.9:1234", "X-Forwarded-For", "198.51.100.1", "203.0.113.9"},
+		{"10.0.0.1:1234", "X-Forwarded-For", "198.51.100.1", "198.51.100.1"},
+		{"10.0.0.1:1234", "X-Forwarded-For", "6.6.6.6, 198.51.100.1, 10.1.1.1", "198.51.100.1"},
+		{"10.0.0.1:1234", "X-Forwarded-For", "10.2.2.2, 10.1.1.1", "10.2.2.2"},
+		{"10.0.0.1:1234", "X-Forwarded-For", "garbage, 10.1.1.1", "10.1.1.1"},
+		{"10.0.0.1:1234", "X-Real-IP", "198.51.100.7", "198.51.100.7"},
+		{"[2001:db8::1]:443", "Forwarded", `for=198.51.100.2;proto=https, for="[2001:db8::2]:4711"`, "198.51.100.2"},
+		{"192.0.2.1:80", "Forwarded", `For="[2001:db9::17]"`, "2001:db9::17"},
+		{"[::ffff:10.0.0.1]:80", "X-Forwarded-For", "198.51.100.3", "198.51.100.3"},
+	} {
+		req := httptest.NewRequest("GET", "http://example.com/ip", nil)
+		req.RemoteAddr = test.remoteAddr
+		req.Header.Set(test.header, test.value)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		expected := test.expected + " " + test.remoteAddr
+		go_http.Assert(t, recorder.Body.String() == expected, "unexpected client ip '%s' for %s: %s", recorder.Body.String(), test.header, test.value)
+	}
+}
+
+func TestRealIPRewritesRemoteAddr(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://example.com/ip", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	recorder := httptest.NewRecorder()
+	realIPRouter(t, true).ServeHTTP(recorder, req)
+	go_http.Assert(t, recorder.Body.String() == "198.51.100.1 198.51.100.1", "unexpected response body '%s'", recorder.Body.String())
+}
+
+func TestRateLimitKeyUsesClientIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://example.com/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	ip, _ := parseIP("198.51.100.1")
+	req = req.WithContext(WithClientIP(req.Context(), ip))
+	go_http.Assert(t, KeyByIP()(req) == "ip:198.51.100.1", "unexpected key %s", KeyByIP()(req))
+}
@@ -0,0 +1,79 @@
+package routing
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+const (
+	contextRequestID = contextKey("router.http.requestId")
+
+	DefaultRequestIDHeader = "X-Request-ID"
+)
+
+type RequestIDConfig struct {
+	Header   string
+	Generate func() string
+	Validate func(id string) bool
+}
+
+func WithRequestID(c context.Context, id string) context.Context {
+	return context.WithValue(c, contextRequestID, id)
+}
+
+func RequestID(c context.Context) string {
+	if value := c.Value(contextRequestID); value != nil {
+		return value.(string)
+	} else {
+		return ""
+	}
+}
+
+func RequestIDFilter(config RequestIDConfig) Filter {
+	if config.Header == "" {
+		config.Header = DefaultRequestIDHeader
+	}
+	if config.Generate == nil {
+		config.Generate = NewUUID
+	}
+	if config.Validate == nil {
+		config.Validate = ValidRequestID
+	}
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		id := r.Header.Get(config.Header)
+		if !config.Validate(id) {
+			id = config.Generate()
+		}
+		// keep the (possibly regenerated) id on the request, so it is forwarded by proxy handlers
+		r.Header.Set(config.Header, id)
+		w.Header().Set(config.Header, id)
+		next(w, r.WithContext(WithRequestID(r.Context(), id)))
+	}
+}
+
+func NewUUID() string {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		panic(err)
+	}
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
+
+func ValidRequestID(id string) bool {
+	if len(id) == 0 || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '+', c == '/', c == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package routing
+
+import (
+	go_http "github.com/mwildt/go-http"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func writeRequestID(writer http.ResponseWriter, request *http.Request) {
+	writer.Write([]byte(RequestID(request.Context())))
+}
+
+func TestRequestIDIsPropagated(t *testing.T) {
+	router := singleRoute(Path("/**").Filter(RequestIDFilter(RequestIDConfig{})), writeRequestID)
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	recorder := serve(router, req)
+
+	go_http.Assert(t, recorder.Body.String() == "abc-123", "unexpected response body %s", recorder.Body.String())
+	go_http.Assert(t, recorder.Header().Get("X-Request-ID") == "abc-123", "unexpected response header %s", recorder.Header().Get("X-Request-ID"))
+}
+
+func TestRequestIDIsGenerated(t *testing.T) {
+	router := singleRoute(Path("/**").Filter(RequestIDFilter(RequestIDConfig{})), writeRequestID)
+	uuidPattern := regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$")
+
+	for _, incoming := range []string{"", "invalid value with spaces"} {
+		req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+		req.Header.Set("X-Request-ID", incoming)
+		recorder := serve(router, req)
+
+		id := recorder.Header().Get("X-Request-ID")
+		go_http.Assert(t, uuidPattern.MatchString(id), "unexpected generated id '%s'", id)
+		go_http.Assert(t, recorder.Body.String() == id, "unexpected response body %s", recorder.Body.String())
+	}
+}
+
+func TestRequestIDCustomHeader(t *testing.T) {
+	router := singleRoute(Path("/**").Filter(RequestIDFilter(RequestIDConfig{Header: "X-Correlation-ID"})), writeRequestID)
+	req := httptest.NewRequest("GET", "http://example.com/foo", nil)
+	req.Header.Set("X-Correlation-ID", "corr-1")
+	recorder := serve(router, req)
+
+	go_http.Assert(t, recorder.Body.String() == "corr-1", "unexpected response body %s", recorder.Body.String())
+	go_http.Assert(t, recorder.Header().Get("X-Correlation-ID") == "corr-1", "unexpected response header")
+}
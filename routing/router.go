@@ -4,12 +4,15 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"strings"
 )
 
 type contextKey string
 
 const (
-	contextParams = contextKey("router.http.params")
+	contextParams  = contextKey("router.http.params")
+	contextRoute   = contextKey("router.http.route")
+	contextMethods = contextKey("router.http.methods")
 )
 
 func WithParameters(c context.Context, parameters Parameters) context.Context {
@@ -30,6 +33,30 @@ func GetParameter(c context.Context, key string) (string, bool) {
 	return value, exists
 }
 
+func WithRoutePattern(c context.Context, pattern string) context.Context {
+	return context.WithValue(c, contextRoute, pattern)
+}
+
+func GetRoutePattern(c context.Context) string {
+	if value := c.Value(contextRoute); value != nil {
+		return value.(string)
+	} else {
+		return ""
+	}
+}
+
+func WithAllowedMethods(c context.Context, methods Methods) context.Context {
+	return context.WithValue(c, contextMethods, methods)
+}
+
+func GetAllowedMethods(c context.Context) Methods {
+	if value := c.Value(contextMethods); value != nil {
+		return value.(Methods)
+	} else {
+		return make(Methods, 0)
+	}
+}
+
 type Methods []string
 
 func (methods Methods) Compare(method string) (match bool) {
@@ -44,6 +71,10 @@ func (methods Methods) Compare(method string) (match bool) {
 	return false
 }
 
+func (methods Methods) contains(method string) bool {
+	return len(methods) > 0 && methods.Compare(method)
+}
+
 func (methods Methods) Extend(methods2 Methods) Methods {
 	if len(methods) == 0 {
 		return methods2
@@ -72,7 +103,21 @@ type matcher struct {
 
 type Route struct {
 	matcher     matcher
+	filterChain FilterChain
 	handlerFunc http.HandlerFunc
+	policy      *Policy
+}
+
+type RouteInfo struct {
+	Path    string
+	Methods Methods
+	// Policy describes the required policies, it is empty for unprotected routes
+	Policy string
+}
+
+func (route Route) serve(writer http.ResponseWriter, request *http.Request, params Parameters, handlerFunc http.HandlerFunc) {
+	ctx := WithRoutePattern(WithParameters(request.Context(), params), route.matcher.path.String())
+	handlerFunc(writer, request.WithContext(ctx))
 }
 
 type Router struct {
@@ -96,14 +141,11 @@ func NewRouter(configurations ...RoutingConsumer) *Router {
 }
 
 func (r *Router) HandleFunc(routeBuilder RouteBuilder, handlerFunc http.HandlerFunc) {
-	r.addRoute(Route{
-		matcher:     routeBuilder.createMatcher(),
-		handlerFunc: routeBuilder.filterChain.Build(handlerFunc),
-	})
+	r.addRoute(routeBuilder.createRoute(handlerFunc))
 }
 
 func (r *Router) Handle(routeBuilder RouteBuilder, handler http.Handler) {
-	r.addRoute(Route{matcher: routeBuilder.createMatcher(), handlerFunc: handler.ServeHTTP})
+	r.HandleFunc(routeBuilder, handler.ServeHTTP)
 }
 
 func (r *Router) Route(matcher RouteBuilder, configurations ...RoutingConsumer) Routing {
@@ -117,21 +159,72 @@ func (r *Router) Route(matcher RouteBuilder, configurations ...RoutingConsumer)
 	return router
 }
 
+func (r *Router) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0, len(r.routes))
+	for _, route := range r.routes {
+		info := RouteInfo{Path: route.matcher.path.String(), Methods: route.matcher.methods}
+		if route.policy != nil {
+			info.Policy = route.policy.String()
+		}
+		routes = append(routes, info)
+	}
+	return routes
+}
+
 func (r *Router) addRoute(route Route) {
 	r.routes = append(r.routes, route)
 }
 
 func (r *Router) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
-	for _, route := range r.routes {
-		if match, _, params := route.matcher.path.Compare(NewUriPath(request.URL.Path)); !match {
+	uriPath := NewUriPath(request.URL.Path)
+	automaticOptions := -1
+	var automaticParams Parameters
+	if request.Method == http.MethodOptions {
+		request = request.WithContext(WithAllowedMethods(request.Context(), r.allowedMethods(uriPath)))
+	}
+	for i, route := range r.routes {
+		if match, _, params := route.matcher.path.Compare(uriPath); !match {
 			continue
 		} else if !route.matcher.methods.Compare(request.Method) {
+			// OPTIONS is answered by the router, unless a route handles it explicitly
+			if request.Method == http.MethodOptions && automaticOptions < 0 {
+				automaticOptions, automaticParams = i, params
+			}
 			continue
+		} else if automaticOptions >= 0 && len(route.matcher.methods) == 0 {
+			// catch-all routes registered later do not shadow the automatic OPTIONS response
+			break
 		} else {
-			route.handlerFunc.ServeHTTP(writer, request.WithContext(WithParameters(request.Context(), params)))
+			route.serve(writer, request, params, route.handlerFunc)
 			return
 		}
 	}
+	if automaticOptions >= 0 {
+		route := r.routes[automaticOptions]
+		route.serve(writer, request, automaticParams, route.filterChain.Build(handleOptions))
+	}
+}
+
+func (r *Router) allowedMethods(uriPath UriPath) Methods {
+	allowed := make(Methods, 0)
+	for _, route := range r.routes {
+		if match, _, _ := route.matcher.path.Compare(uriPath); match {
+			for _, method := range route.matcher.methods {
+				if !allowed.contains(method) {
+					allowed = append(allowed, method)
+				}
+			}
+		}
+	}
+	if len(allowed) > 0 && !allowed.contains(http.MethodOptions) {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	return allowed
+}
+
+func handleOptions(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Allow", strings.Join(GetAllowedMethods(request.Context()), ", "))
+	writer.WriteHeader(http.StatusNoContent)
 }
 
 func DefaultNotFound() RoutingConsumer {
@@ -146,11 +239,7 @@ type subrouter struct {
 }
 
 func (r *subrouter) HandleFunc(builder RouteBuilder, handlerFunc http.HandlerFunc) {
-	extendBuilder := r.routeBuilder.extend(builder)
-	r.router.addRoute(Route{
-		matcher:     extendBuilder.createMatcher(),
-		handlerFunc: extendBuilder.filterChain.Build(handlerFunc),
-	})
+	r.router.addRoute(r.routeBuilder.extend(builder).createRoute(handlerFunc))
 }
 
 func (r *subrouter) Handle(builder RouteBuilder, handler http.Handler) {
@@ -102,6 +102,25 @@ func TestRoutingWithFilter(t *testing.T) {
 	go_http.Assert(t, logs[0] == "http://example.com/routing/test", "unexpected frist log statement", len(logs[0]))
 }
 
+func TestHandleWithFilter(t *testing.T) {
+	router := NewRouter()
+
+	var logs []string
+
+	logFilter := func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		logs = append(logs, r.URL.String())
+		next(w, r)
+	}
+
+	router.Handle(Path("/routing/test").Filter(logFilter), http.NotFoundHandler())
+
+	req := httptest.NewRequest("GET", "http://example.com/routing/test", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	go_http.Assert(t, recorder.Code == 404, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, len(logs) == 1, "unexpected lenght of log %d", len(logs))
+}
+
 func TestRoutingWithMulltipleFilters(t *testing.T) {
 	router := NewRouter()
 
@@ -150,3 +169,16 @@ func TestRoutingWithAlternatives(t *testing.T) {
 	go_http.Assert(t, recorder.Body.String() == "sub2", "unexpected response body '%s'", recorder.Body.String())
 
 }
+
+// singleRoute builds a router with one route, the usual setup of filter tests
+func singleRoute(builder RouteBuilder, handlerFunc http.HandlerFunc) *Router {
+	return NewRouter(func(router Routing) {
+		router.HandleFunc(builder, handlerFunc)
+	})
+}
+
+func serve(handler http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
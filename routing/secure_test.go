@@ -0,0 +1,61 @@
+package routing
+
+import (
+	"crypto/tls"
+	go_http "github.com/mwildt/go-http"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSecureHeadersDefaults(t *testing.T) {
+	var nonce string
+	router := NewRouter(func(router Routing) {
+		router.HandleFunc(Get("/page").Filter(SecureHeaders(DefaultSecureHeaders())), func(writer http.ResponseWriter, request *http.Request) {
+			nonce = CSPNonce(request.Context())
+		})
+	})
+
+	req := httptest.NewRequest("GET", "https://example.com/page", nil)
+	req.TLS = &tls.ConnectionState{}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	header := recorder.Header()
+	go_http.Assert(t, header.Get("Strict-Transport-Security") == "max-age=31536000; includeSubDomains", "unexpected hsts '%s'", header.Get("Strict-Transport-Security"))
+	go_http.Assert(t, header.Get("X-Content-Type-Options") == "nosniff", "missing nosniff")
+	go_http.Assert(t, header.Get("X-Frame-Options") == "DENY", "unexpected frame options")
+	go_http.Assert(t, header.Get("Cross-Origin-Opener-Policy") == "same-origin", "unexpected opener policy")
+	go_http.Assert(t, header.Get("Cross-Origin-Embedder-Policy") == "", "embedder policy is not set by default")
+	go_http.Assert(t, len(nonce) > 0, "missing nonce in context")
+	go_http.Assert(t, strings.Contains(header.Get("Content-Security-Policy"), "'nonce-"+nonce+"'"), "nonce not in policy '%s'", header.Get("Content-Security-Policy"))
+
+	firstNonce := nonce
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "http://example.com/page", nil))
+	go_http.Assert(t, recorder.Header().Get("Strict-Transport-Security") == "", "hsts must not be sent over http")
+	go_http.Assert(t, nonce != firstNonce, "nonce reused")
+}
+
+func TestSecureHeadersRouteOverride(t *testing.T) {
+	embeddable := SecureHeadersConfig{FrameOptions: "SAMEORIGIN", ContentSecurityPolicy: "frame-ancestors 'self'"}
+	router := NewRouter(func(router Routing) {
+		router.Route(Filtering(SecureHeaders(DefaultSecureHeaders())), func(router Routing) {
+			router.HandleFunc(Get("/widget").Filter(SecureHeaders(embeddable)), func(writer http.ResponseWriter, request *http.Request) {
+				go_http.Assert(t, CSPNonce(request.Context()) == "", "nonce of the overridden policy must not be used")
+			})
+			router.HandleFunc(Get("/page"), func(writer http.ResponseWriter, request *http.Request) {})
+		})
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "http://example.com/widget", nil))
+	go_http.Assert(t, recorder.Header().Get("X-Frame-Options") == "SAMEORIGIN", "unexpected frame options %s", recorder.Header().Get("X-Frame-Options"))
+	go_http.Assert(t, recorder.Header().Get("Content-Security-Policy") == "frame-ancestors 'self'", "unexpected policy")
+	go_http.Assert(t, recorder.Header().Get("Referrer-Policy") == "strict-origin-when-cross-origin", "parent headers must be kept")
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "http://example.com/page", nil))
+	go_http.Assert(t, recorder.Header().Get("X-Frame-Options") == "DENY", "override leaked to sibling route")
+}
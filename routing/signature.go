@@ -0,0 +1,126 @@
+package routing
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mwildt/go-http/httputils"
+)
+
+type SignatureEncoding int
+
+const (
+	HexSignature SignatureEncoding = iota
+	Base64Signature
+)
+
+type SignatureConfig struct {
+	// Secrets are all accepted, to allow the rotation of secrets
+	Secrets [][]byte
+	Header  string
+	// Hash defaults to sha256.New, use sha1.New for providers still signing with HMAC-SHA1
+	Hash     func() hash.Hash
+	Encoding SignatureEncoding
+	// Prefix is removed from the header value, e.g. "sha256="
+	Prefix string
+	// Timestamped expects headers like "t=1492774577,v1=5257a869...", the signed payload is "<t>.<body>"
+	Timestamped  bool
+	SignatureKey string
+	ReplayWindow time.Duration
+	MaxBodySize  int64
+}
+
+func VerifySignature(config SignatureConfig) Filter {
+	if config.Hash == nil {
+		config.Hash = sha256.New
+	}
+	if config.SignatureKey == "" {
+		config.SignatureKey = "v1"
+	}
+	if config.ReplayWindow == 0 {
+		config.ReplayWindow = 5 * time.Minute
+	}
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = 1 << 20
+	}
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if r.ContentLength > config.MaxBodySize {
+			httputils.Send(w, r, http.StatusRequestEntityTooLarge)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, config.MaxBodySize+1))
+		r.Body.Close()
+		if err != nil {
+			httputils.BadRequest(w, r)
+			return
+		} else if int64(len(body)) > config.MaxBodySize {
+			httputils.Send(w, r, http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if !config.verify(r.Header.Get(config.Header), body, time.Now()) {
+			httputils.Unauthorized(w, r)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		next(w, r)
+	}
+}
+
+func (config SignatureConfig) verify(header string, body []byte, now time.Time) bool {
+	payload := body
+	signatures := make([]string, 0)
+	if config.Timestamped {
+		var timestamp string
+		for _, part := range strings.Split(header, ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if key == "t" {
+				timestamp = value
+			} else if key == config.SignatureKey {
+				signatures = append(signatures, value)
+			}
+		}
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return false
+		}
+		if age := now.Sub(time.Unix(seconds, 0)); age > config.ReplayWindow || age < -config.ReplayWindow {
+			return false
+		}
+		payload = append([]byte(timestamp+"."), body...)
+	} else if signature, found := strings.CutPrefix(strings.TrimSpace(header), config.Prefix); found {
+		signatures = append(signatures, signature)
+	}
+
+	for _, signature := range signatures {
+		decoded, err := config.decode(signature)
+		if err != nil {
+			continue
+		}
+		for _, secret := range config.Secrets {
+			mac := hmac.New(config.Hash, secret)
+			mac.Write(payload)
+			if hmac.Equal(mac.Sum(nil), decoded) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (config SignatureConfig) decode(signature string) ([]byte, error) {
+	if config.Encoding == Base64Signature {
+		return base64.StdEncoding.DecodeString(signature)
+	}
+	return hex.DecodeString(strings.ToLower(signature))
+}
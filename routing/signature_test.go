@@ -0,0 +1,79 @@
+package routing
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	go_http "github.com/mwildt/go-http"
+	"hash"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func hmacSign(hashFunc func() hash.Hash, secret string, payload string) []byte {
+	mac := hmac.New(hashFunc, []byte(secret))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func echoWebhook(writer http.ResponseWriter, request *http.Request) {
+	body, _ := io.ReadAll(request.Body)
+	writer.Write(body)
+}
+
+func webhookRequest(header string, signature string, body string) *http.Request {
+	req := httptest.NewRequest("POST", "http://example.com/hook", strings.NewReader(body))
+	req.Header.Set(header, signature)
+	return req
+}
+
+func TestVerifySignatureHex(t *testing.T) {
+	router := singleRoute(Post("/hook").Filter(VerifySignature(SignatureConfig{
+		Secrets: [][]byte{[]byte("new"), []byte("old")},
+		Header:  "X-Hub-Signature-256",
+		Prefix:  "sha256=",
+	})), echoWebhook)
+	body := `{"action":"opened"}`
+
+	for _, secret := range []string{"new", "old"} {
+		recorder := serve(router, webhookRequest("X-Hub-Signature-256", "sha256="+hex.EncodeToString(hmacSign(sha256.New, secret, body)), body))
+		go_http.Assert(t, recorder.Code == 200, "unexpected response status %d", recorder.Code)
+		go_http.Assert(t, recorder.Body.String() == body, "body not restored %s", recorder.Body.String())
+	}
+
+	go_http.Assert(t, serve(router, webhookRequest("X-Hub-Signature-256", "sha256="+hex.EncodeToString(hmacSign(sha256.New, "wrong", body)), body)).Code == 401, "wrong secret accepted")
+	go_http.Assert(t, serve(router, webhookRequest("X-Hub-Signature-256", hex.EncodeToString(hmacSign(sha256.New, "new", body)), body)).Code == 401, "missing prefix accepted")
+	go_http.Assert(t, serve(router, webhookRequest("X-Hub-Signature-256", "sha256="+hex.EncodeToString(hmacSign(sha256.New, "new", body)), body+" ")).Code == 401, "modified body accepted")
+}
+
+func TestVerifySignatureBase64SHA1(t *testing.T) {
+	router := singleRoute(Post("/hook").Filter(VerifySignature(SignatureConfig{Secrets: [][]byte{[]byte("secret")}, Header: "X-Signature", Hash: sha1.New, Encoding: Base64Signature, MaxBodySize: 16})), echoWebhook)
+	body := "payload"
+
+	recorder := serve(router, webhookRequest("X-Signature", base64.StdEncoding.EncodeToString(hmacSign(sha1.New, "secret", body)), body))
+	go_http.Assert(t, recorder.Code == 200, "unexpected response status %d", recorder.Code)
+
+	recorder = serve(router, webhookRequest("X-Signature", "", strings.Repeat("x", 17)))
+	go_http.Assert(t, recorder.Code == 413, "unexpected response status %d", recorder.Code)
+}
+
+func TestVerifySignatureTimestamped(t *testing.T) {
+	router := singleRoute(Post("/hook").Filter(VerifySignature(SignatureConfig{Secrets: [][]byte{[]byte("whsec")}, Header: "Stripe-Signature", Timestamped: true, ReplayWindow: time.Minute})), echoWebhook)
+	body := `{"id":"evt_1"}`
+	header := func(timestamp time.Time, secret string) string {
+		t := strconv.FormatInt(timestamp.Unix(), 10)
+		return "t=" + t + ",v1=" + hex.EncodeToString(hmacSign(sha256.New, "other", t+"."+body)) + ",v1=" + hex.EncodeToString(hmacSign(sha256.New, secret, t+"."+body))
+	}
+
+	go_http.Assert(t, serve(router, webhookRequest("Stripe-Signature", header(time.Now(), "whsec"), body)).Code == 200, "valid signature rejected")
+	go_http.Assert(t, serve(router, webhookRequest("Stripe-Signature", header(time.Now().Add(-2*time.Minute), "whsec"), body)).Code == 401, "replayed request accepted")
+	go_http.Assert(t, serve(router, webhookRequest("Stripe-Signature", header(time.Now(), "wrong"), body)).Code == 401, "wrong secret accepted")
+	go_http.Assert(t, serve(router, webhookRequest("Stripe-Signature", "v1=abc", body)).Code == 401, "missing timestamp accepted")
+}
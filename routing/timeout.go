@@ -0,0 +1,156 @@
+package routing
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mwildt/go-http/httputils"
+)
+
+const contextTimeout = contextKey("router.http.timeout")
+
+type TimeoutOptions struct {
+	// Status of the timeout response, defaults to 503
+	Status int
+	// Message defaults to the status text
+	Message string
+	// Problem sends the timeout response as application/problem+json
+	Problem bool
+}
+
+type timeoutControl struct {
+	mutex    sync.Mutex
+	deadline time.Time
+	timer    *time.Timer
+	expired  chan struct{}
+	options  TimeoutOptions
+}
+
+func (control *timeoutControl) reset(d time.Duration, options TimeoutOptions) {
+	control.mutex.Lock()
+	defer control.mutex.Unlock()
+	if control.timer.Stop() {
+		control.deadline = time.Now().Add(d)
+		control.timer.Reset(d)
+		control.options = options
+	}
+}
+
+func (control *timeoutControl) isExpired() bool {
+	select {
+	case <-control.expired:
+		return true
+	default:
+		return false
+	}
+}
+
+// timeoutContext reports the deadline of the control, which may be changed by nested Timeout filters
+type timeoutContext struct {
+	context.Context
+	control *timeoutControl
+}
+
+func (c timeoutContext) Deadline() (time.Time, bool) {
+	c.control.mutex.Lock()
+	defer c.control.mutex.Unlock()
+	return c.control.deadline, true
+}
+
+func (c timeoutContext) Err() error {
+	if err := c.Context.Err(); err != nil && c.control.isExpired() {
+		return context.DeadlineExceeded
+	} else {
+		return err
+	}
+}
+
+type timeoutWriter struct {
+	mutex    sync.Mutex
+	buffer   *responseBuffer
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.buffer.Header()
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+	if !tw.timedOut {
+		tw.buffer.WriteHeader(code)
+	}
+}
+
+func (tw *timeoutWriter) Write(data []byte) (int, error) {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	return tw.buffer.Write(data)
+}
+
+// Timeout buffers the response of the handler and replaces it by a timeout response when the handler
+// does not finish within d. A Timeout filter nested in another one overrides its duration and options.
+func Timeout(d time.Duration, options TimeoutOptions) Filter {
+	if options.Status == 0 {
+		options.Status = http.StatusServiceUnavailable
+	}
+	if options.Message == "" {
+		options.Message = http.StatusText(options.Status)
+	}
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if control, nested := r.Context().Value(contextTimeout).(*timeoutControl); nested {
+			control.reset(d, options)
+			next(w, r)
+			return
+		}
+
+		control := &timeoutControl{deadline: time.Now().Add(d), expired: make(chan struct{}), options: options}
+		control.timer = time.AfterFunc(d, func() { close(control.expired) })
+		defer control.timer.Stop()
+
+		ctx, cancel := context.WithCancel(context.WithValue(r.Context(), contextTimeout, control))
+		defer cancel()
+
+		tw := &timeoutWriter{buffer: newResponseBuffer()}
+		done := make(chan struct{})
+		panics := make(chan any, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panics <- p
+				}
+			}()
+			next(tw, r.WithContext(timeoutContext{ctx, control}))
+			close(done)
+		}()
+
+		select {
+		case p := <-panics:
+			panic(p)
+		case <-done:
+			tw.mutex.Lock()
+			defer tw.mutex.Unlock()
+			tw.buffer.copyTo(w)
+		case <-control.expired:
+			tw.mutex.Lock()
+			tw.timedOut = true
+			tw.mutex.Unlock()
+			cancel()
+
+			control.mutex.Lock()
+			options := control.options
+			control.mutex.Unlock()
+			if options.Problem {
+				httputils.SendProblem(w, r, httputils.NewProblem(options.Status, options.Message))
+			} else {
+				httputils.SendText(w, r, options.Status, options.Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	go_http "github.com/mwildt/go-http"
+	"github.com/mwildt/go-http/httputils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeoutNotReached(t *testing.T) {
+	router := NewRouter(func(router Routing) {
+		router.HandleFunc(Get("/fast").Filter(Timeout(time.Second, TimeoutOptions{})), func(writer http.ResponseWriter, request *http.Request) {
+			_, hasDeadline := request.Context().Deadline()
+			go_http.Assert(t, hasDeadline, "missing context deadline")
+			writer.Header().Set("X-Test", "value")
+			writer.WriteHeader(201)
+			writer.Write([]byte("FAST"))
+		})
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "http://example.com/fast", nil))
+	go_http.Assert(t, recorder.Code == 201, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, recorder.Body.String() == "FAST", "unexpected response body %s", recorder.Body.String())
+	go_http.Assert(t, recorder.Header().Get("X-Test") == "value", "missing response header")
+}
+
+func TestTimeoutReached(t *testing.T) {
+	lateWrite := make(chan error, 1)
+	router := NewRouter(func(router Routing) {
+		router.HandleFunc(Get("/slow").Filter(Timeout(10*time.Millisecond, TimeoutOptions{Message: "too slow"})), func(writer http.ResponseWriter, request *http.Request) {
+			<-request.Context().Done()
+			go_http.Assert(t, request.Context().Err() == context.DeadlineExceeded, "unexpected context error %v", request.Context().Err())
+			_, err := writer.Write([]byte("LATE"))
+			lateWrite <- err
+		})
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "http://example.com/slow", nil))
+	go_http.Assert(t, recorder.Code == 503, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, recorder.Body.String() == "too slow", "unexpected response body %s", recorder.Body.String())
+	go_http.Assert(t, <-lateWrite == http.ErrHandlerTimeout, "late write not rejected")
+}
+
+func TestTimeoutProblemAndOverride(t *testing.T) {
+	slow := func(writer http.ResponseWriter, request *http.Request) {
+		select {
+		case <-request.Context().Done():
+		case <-time.After(50 * time.Millisecond):
+			writer.Write([]byte("DONE"))
+		}
+	}
+	router := NewRouter(func(router Routing) {
+		router.Route(Path("/api").Filter(Timeout(10*time.Millisecond, TimeoutOptions{Status: 504, Problem: true})), func(router Routing) {
+			router.HandleFunc(Get("/default"), slow)
+			router.HandleFunc(Get("/upload").Filter(Timeout(time.Second, TimeoutOptions{})), slow)
+		})
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "http://example.com/api/default", nil))
+	go_http.Assert(t, recorder.Code == 504, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, recorder.Header().Get("Content-Type") == "application/problem+json", "unexpected content type")
+	var problem httputils.Problem
+	go_http.AssertNoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem), "invalid problem body")
+	go_http.Assert(t, problem.Status == 504 && problem.Title == "Gateway Timeout" && problem.Detail == "Gateway Timeout", "unexpected problem %v", problem)
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "http://example.com/api/upload", nil))
+	go_http.Assert(t, recorder.Code == 200, "unexpected response status %d", recorder.Code)
+	go_http.Assert(t, recorder.Body.String() == "DONE", "unexpected response body %s", recorder.Body.String())
+}
+
+func TestTimeoutKeepsOuterListHeaders(t *testing.T) {
+	router := NewRouter(func(router Routing) {
+		router.HandleFunc(Get("/fast").Filter(Compress(CompressionConfig{})).Filter(Timeout(time.Second, TimeoutOptions{})), func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("Vary", "Accept-Language")
+			writer.Header().Set("Content-Type", "text/plain")
+			writer.Write([]byte("FAST"))
+		})
+	})
+
+	req := httptest.NewRequest("GET", "http://example.com/fast", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	vary := recorder.Header().Values("Vary")
+	go_http.Assert(t, len(vary) == 2 && vary[0] == "Accept-Encoding" && vary[1] == "Accept-Language", "outer vary header lost %v", vary)
+}
@@ -0,0 +1,175 @@
+package sessions
+
+import (
+	"crypto/aes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/mwildt/go-http/httputils"
+)
+
+type Config struct {
+	CookieName string
+	Path       string
+	Domain     string
+	// Insecure allows the session cookie over plain http
+	Insecure bool
+	SameSite http.SameSite
+	// Keys are AES keys of 16, 24 or 32 bytes, the first one encrypts, all of them decrypt
+	Keys            [][]byte
+	IdleTimeout     time.Duration
+	AbsoluteTimeout time.Duration
+	Store           Store
+}
+
+type Manager struct {
+	config Config
+}
+
+func New(config Config) (*Manager, error) {
+	if config.CookieName == "" {
+		config.CookieName = "session"
+	}
+	if config.Path == "" {
+		config.Path = "/"
+	}
+	if config.SameSite == 0 {
+		config.SameSite = http.SameSiteLaxMode
+	}
+	if config.IdleTimeout == 0 {
+		config.IdleTimeout = 30 * time.Minute
+	}
+	if config.AbsoluteTimeout == 0 {
+		config.AbsoluteTimeout = 24 * time.Hour
+	}
+	if config.Store == nil {
+		config.Store = CookieStore{}
+	}
+	if len(config.Keys) == 0 {
+		return nil, errors.New("sessions: at least one key is required")
+	}
+	for _, key := range config.Keys {
+		if _, err := aes.NewCipher(key); err != nil {
+			return nil, err
+		}
+	}
+	return &Manager{config: config}, nil
+}
+
+func (manager *Manager) Filter(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	session := manager.load(r, time.Now())
+	writer := &sessionWriter{ResponseWriter: w, manager: manager, session: session}
+	next(writer, r.WithContext(WithSession(r.Context(), session)))
+	writer.commit()
+}
+
+func (manager *Manager) load(r *http.Request, now time.Time) *Session {
+	ref, err := httputils.GetEncryptedCookie(r, manager.config.CookieName, manager.config.Keys)
+	if err != nil {
+		return newSession(now)
+	}
+	record, err := manager.config.Store.Load(ref)
+	if err != nil {
+		return newSession(now)
+	}
+	if now.Sub(record.Accessed) > manager.config.IdleTimeout || now.Sub(record.Created) > manager.config.AbsoluteTimeout {
+		manager.config.Store.Delete(ref)
+		return newSession(now)
+	}
+	if record.Values == nil {
+		record.Values = make(map[string]json.RawMessage)
+	}
+	return &Session{record: record, ref: ref}
+}
+
+func (manager *Manager) cookieOptions() httputils.CookieOptions {
+	return httputils.CookieOptions{
+		Path:     manager.config.Path,
+		Domain:   manager.config.Domain,
+		SameSite: manager.config.SameSite,
+		Insecure: manager.config.Insecure,
+	}
+}
+
+// save persists the session and sets the cookie if one is to be sent
+func (manager *Manager) save(w http.ResponseWriter, session *Session, now time.Time) error {
+	options := manager.cookieOptions()
+	if session.destroyed {
+		if session.ref != "" {
+			manager.config.Store.Delete(session.ref)
+		}
+		httputils.DeleteCookie(w, manager.config.CookieName, options)
+		return nil
+	}
+	if session.isNew && !session.modified {
+		return nil
+	}
+	if session.rotated && session.ref != "" {
+		manager.config.Store.Delete(session.ref)
+	}
+	session.record.Accessed = now
+	ref, err := manager.config.Store.Save(session.record)
+	if err != nil {
+		return err
+	}
+	session.ref = ref
+	options.MaxAge = session.record.Created.Add(manager.config.AbsoluteTimeout).Sub(now)
+	return httputils.SetEncryptedCookie(w, manager.config.CookieName, ref, manager.config.Keys, options)
+}
+
+// sessionWriter saves the session before the response header is written, so the cookie can still be set
+type sessionWriter struct {
+	http.ResponseWriter
+	manager   *Manager
+	session   *Session
+	committed bool
+}
+
+func (writer *sessionWriter) commit() {
+	session := writer.session
+	if !writer.committed {
+		writer.committed = true
+		if err := writer.manager.save(writer.ResponseWriter, session, time.Now()); err != nil {
+			log.Printf("sessions: saving session failed: %v", err)
+		}
+		session.modified = false
+		session.rotated = false
+	} else if session.modified && !session.destroyed && !session.rotated && session.ref == session.record.ID {
+		// the cookie is already sent, only stores referencing sessions by id can take later changes
+		if err := writer.manager.save(discardHeader{}, session, time.Now()); err != nil {
+			log.Printf("sessions: saving session failed: %v", err)
+		}
+		session.modified = false
+	}
+}
+
+func (writer *sessionWriter) WriteHeader(code int) {
+	writer.commit()
+	writer.ResponseWriter.WriteHeader(code)
+}
+
+func (writer *sessionWriter) Write(data []byte) (int, error) {
+	writer.commit()
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *sessionWriter) Flush() {
+	writer.commit()
+	if flusher, ok := writer.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (writer *sessionWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// discardHeader takes the cookie of saves after the response header is already sent
+type discardHeader struct{}
+
+func (discardHeader) Header() http.Header            { return http.Header{} }
+func (discardHeader) Write(data []byte) (int, error) { return len(data), nil }
+func (discardHeader) WriteHeader(int)                {}
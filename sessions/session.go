@@ -0,0 +1,109 @@
+package sessions
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+type contextKey string
+
+const contextSession = contextKey("sessions.session")
+
+// Record is the persisted state of a session
+type Record struct {
+	ID       string                     `json:"id"`
+	Values   map[string]json.RawMessage `json:"values"`
+	Created  time.Time                  `json:"created"`
+	Accessed time.Time                  `json:"accessed"`
+}
+
+type Session struct {
+	record    Record
+	ref       string
+	isNew     bool
+	modified  bool
+	rotated   bool
+	destroyed bool
+}
+
+func newSession(now time.Time) *Session {
+	return &Session{
+		record: Record{ID: newID(), Values: make(map[string]json.RawMessage), Created: now, Accessed: now},
+		isNew:  true,
+	}
+}
+
+func newID() string {
+	var id [32]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(id[:])
+}
+
+func (session *Session) ID() string {
+	return session.record.ID
+}
+
+func (session *Session) IsNew() bool {
+	return session.isNew
+}
+
+func (session *Session) Set(key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	session.record.Values[key] = data
+	session.modified = true
+	return nil
+}
+
+func (session *Session) Delete(key string) {
+	delete(session.record.Values, key)
+	session.modified = true
+}
+
+// Rotate issues a new session id and keeps the values, call it on login to prevent session fixation
+func (session *Session) Rotate() {
+	session.record.ID = newID()
+	session.rotated = true
+}
+
+// Destroy removes the session from the store and expires the cookie, e.g. on logout
+func (session *Session) Destroy() {
+	session.destroyed = true
+}
+
+func WithSession(c context.Context, session *Session) context.Context {
+	return context.WithValue(c, contextSession, session)
+}
+
+func FromContext(c context.Context) (*Session, bool) {
+	session, ok := c.Value(contextSession).(*Session)
+	return session, ok
+}
+
+// Get returns the value of key decoded into T, it is false if the value is missing or not a T
+func Get[T any](c context.Context, key string) (value T, ok bool) {
+	session, exists := FromContext(c)
+	if !exists {
+		return value, false
+	}
+	data, exists := session.record.Values[key]
+	if !exists {
+		return value, false
+	}
+	return value, json.Unmarshal(data, &value) == nil
+}
+
+func Set(c context.Context, key string, value any) error {
+	session, exists := FromContext(c)
+	if !exists {
+		return ErrNoSession
+	}
+	return session.Set(key, value)
+}
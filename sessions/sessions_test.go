@@ -0,0 +1,150 @@
+package sessions
+
+import (
+	go_http "github.com/mwildt/go-http"
+	"github.com/mwildt/go-http/httputils"
+	"github.com/mwildt/go-http/routing"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+type user struct {
+	Name  string
+	Admin bool
+}
+
+var (
+	key1 = []byte("0123456789abcdef0123456789abcdef")
+	key2 = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func sessionRouter(manager *Manager) *routing.Router {
+	return routing.NewRouter(func(router routing.Routing) {
+		router.Route(routing.Filtering(manager.Filter), func(router routing.Routing) {
+			router.HandleFunc(routing.Post("/login"), func(writer http.ResponseWriter, request *http.Request) {
+				session, _ := FromContext(request.Context())
+				session.Rotate()
+				Set(request.Context(), "user", user{Name: "alice", Admin: true})
+			})
+			router.HandleFunc(routing.Get("/me"), func(writer http.ResponseWriter, request *http.Request) {
+				if u, ok := Get[user](request.Context(), "user"); ok {
+					writer.Write([]byte(u.Name))
+				} else {
+					writer.WriteHeader(http.StatusUnauthorized)
+				}
+			})
+			router.HandleFunc(routing.Post("/logout"), func(writer http.ResponseWriter, request *http.Request) {
+				session, _ := FromContext(request.Context())
+				session.Destroy()
+			})
+		})
+	})
+}
+
+func serveSession(router http.Handler, method string, path string, cookie *http.Cookie) (*httptest.ResponseRecorder, *http.Cookie) {
+	req := httptest.NewRequest(method, "http://example.com"+path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	for _, c := range recorder.Result().Cookies() {
+		return recorder, c
+	}
+	return recorder, nil
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	dir := t.TempDir()
+	fileStore, err := NewFileStore(dir)
+	go_http.AssertNoError(t, err, "creating file store failed")
+
+	for name, store := range map[string]Store{"cookie": CookieStore{}, "memory": NewMemoryStore(time.Hour), "file": fileStore} {
+		manager, err := New(Config{Keys: [][]byte{key1}, Store: store})
+		go_http.AssertNoError(t, err, "creating manager failed")
+		router := sessionRouter(manager)
+
+		recorder, cookie := serveSession(router, "GET", "/me", nil)
+		go_http.Assert(t, recorder.Code == 401 && cookie == nil, "%s: unmodified new session must not be saved", name)
+
+		_, cookie = serveSession(router, "POST", "/login", nil)
+		go_http.Assert(t, cookie != nil && cookie.HttpOnly && cookie.Secure && cookie.SameSite == http.SameSiteLaxMode, "%s: missing session cookie", name)
+
+		recorder, _ = serveSession(router, "GET", "/me", cookie)
+		go_http.Assert(t, recorder.Body.String() == "alice", "%s: unexpected user '%s'", name, recorder.Body.String())
+
+		_, expired := serveSession(router, "POST", "/logout", cookie)
+		go_http.Assert(t, expired != nil && expired.MaxAge < 0, "%s: cookie not expired on logout", name)
+		if name != "cookie" {
+			recorder, _ = serveSession(router, "GET", "/me", cookie)
+			go_http.Assert(t, recorder.Code == 401, "%s: destroyed session still valid", name)
+		}
+	}
+
+	files, _ := os.ReadDir(dir)
+	go_http.Assert(t, len(files) == 0, "destroyed session files not removed %d", len(files))
+}
+
+func cookieRef(cookie *http.Cookie) string {
+	req := httptest.NewRequest("GET", "http://example.com/", nil)
+	req.AddCookie(cookie)
+	ref, _ := httputils.GetEncryptedCookie(req, cookie.Name, [][]byte{key1})
+	return ref
+}
+
+func TestSessionRotation(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	manager, _ := New(Config{Keys: [][]byte{key1}, Store: store})
+	router := sessionRouter(manager)
+
+	_, first := serveSession(router, "POST", "/login", nil)
+	firstID := cookieRef(first)
+	_, second := serveSession(router, "POST", "/login", first)
+	secondID := cookieRef(second)
+
+	go_http.Assert(t, firstID != secondID, "session id not rotated")
+	_, err := store.Load(firstID)
+	go_http.Assert(t, err == ErrNotFound, "old session not deleted")
+	recorder, _ := serveSession(router, "GET", "/me", second)
+	go_http.Assert(t, recorder.Body.String() == "alice", "values lost on rotation")
+}
+
+func TestSessionTimeouts(t *testing.T) {
+	manager, _ := New(Config{Keys: [][]byte{key1}, IdleTimeout: time.Minute, AbsoluteTimeout: time.Hour})
+	_, cookie := serveSession(sessionRouter(manager), "POST", "/login", nil)
+	req := httptest.NewRequest("GET", "http://example.com/me", nil)
+	req.AddCookie(cookie)
+
+	go_http.Assert(t, !manager.load(req, time.Now().Add(30*time.Second)).IsNew(), "session expired too early")
+	go_http.Assert(t, manager.load(req, time.Now().Add(2*time.Minute)).IsNew(), "idle timeout not enforced")
+
+	session := manager.load(req, time.Now())
+	session.record.Accessed = time.Now().Add(2 * time.Hour)
+	recorder := httptest.NewRecorder()
+	manager.save(recorder, session, time.Now().Add(2*time.Hour))
+	req = httptest.NewRequest("GET", "http://example.com/me", nil)
+	req.AddCookie(recorder.Result().Cookies()[0])
+	go_http.Assert(t, manager.load(req, time.Now().Add(2*time.Hour)).IsNew(), "absolute timeout not enforced")
+}
+
+func TestSessionKeyRotation(t *testing.T) {
+	oldManager, _ := New(Config{Keys: [][]byte{key1}})
+	newManager, _ := New(Config{Keys: [][]byte{key2, key1}})
+	otherManager, _ := New(Config{Keys: [][]byte{key2}})
+
+	_, cookie := serveSession(sessionRouter(oldManager), "POST", "/login", nil)
+	recorder, _ := serveSession(sessionRouter(newManager), "GET", "/me", cookie)
+	go_http.Assert(t, recorder.Body.String() == "alice", "cookie of previous key rejected")
+	recorder, _ = serveSession(sessionRouter(otherManager), "GET", "/me", cookie)
+	go_http.Assert(t, recorder.Code == 401, "cookie of unknown key accepted")
+
+	cookie.Value = cookie.Value[:len(cookie.Value)-2] + "AA"
+	recorder, _ = serveSession(sessionRouter(oldManager), "GET", "/me", cookie)
+	go_http.Assert(t, recorder.Code == 401, "tampered cookie accepted")
+
+	_, err := New(Config{Keys: [][]byte{[]byte("short")}})
+	go_http.Assert(t, err != nil, "invalid key accepted")
+}
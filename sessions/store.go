@@ -0,0 +1,159 @@
+package sessions
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoSession = errors.New("no session in context")
+	ErrNotFound  = errors.New("session not found")
+)
+
+// Store persists session records, the returned reference is stored (encrypted) in the cookie.
+// Server side stores use the session id as reference, the CookieStore the record itself.
+type Store interface {
+	Load(ref string) (Record, error)
+	Save(record Record) (ref string, err error)
+	Delete(ref string) error
+}
+
+type CookieStore struct{}
+
+func (CookieStore) Load(ref string) (record Record, err error) {
+	err = json.Unmarshal([]byte(ref), &record)
+	return record, err
+}
+
+func (CookieStore) Save(record Record) (string, error) {
+	data, err := json.Marshal(record)
+	return string(data), err
+}
+
+func (CookieStore) Delete(ref string) error {
+	return nil
+}
+
+type memoryEntry struct {
+	record  Record
+	expires time.Time
+}
+
+type MemoryStore struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	entries map[string]memoryEntry
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{ttl: ttl, entries: make(map[string]memoryEntry)}
+}
+
+func (store *MemoryStore) Load(ref string) (Record, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	entry, exists := store.entries[ref]
+	if !exists || time.Now().After(entry.expires) {
+		delete(store.entries, ref)
+		return Record{}, ErrNotFound
+	}
+	return entry.record, nil
+}
+
+func (store *MemoryStore) Save(record Record) (string, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	now := time.Now()
+	for id, entry := range store.entries {
+		if now.After(entry.expires) {
+			delete(store.entries, id)
+		}
+	}
+	store.entries[record.ID] = memoryEntry{record: record, expires: now.Add(store.ttl)}
+	return record.ID, nil
+}
+
+func (store *MemoryStore) Delete(ref string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.entries, ref)
+	return nil
+}
+
+var validID = regexp.MustCompile("^[A-Za-z0-9_-]+$")
+
+// FileStore keeps one json file per session in a directory
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	return &FileStore{dir: dir}, os.MkdirAll(dir, 0700)
+}
+
+func (store *FileStore) path(id string) (string, error) {
+	if !validID.MatchString(id) {
+		return "", ErrNotFound
+	}
+	return filepath.Join(store.dir, id+".json"), nil
+}
+
+func (store *FileStore) Load(ref string) (record Record, err error) {
+	path, err := store.path(ref)
+	if err != nil {
+		return record, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return record, ErrNotFound
+	} else if err != nil {
+		return record, err
+	}
+	return record, json.Unmarshal(data, &record)
+}
+
+func (store *FileStore) Save(record Record) (string, error) {
+	path, err := store.path(record.ID)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, data, 0600); err != nil {
+		return "", err
+	}
+	return record.ID, os.Rename(temp, path)
+}
+
+func (store *FileStore) Delete(ref string) error {
+	path, err := store.path(ref)
+	if err != nil {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Cleanup removes sessions which were not accessed within maxIdle
+func (store *FileStore) Cleanup(maxIdle time.Duration) error {
+	files, err := filepath.Glob(filepath.Join(store.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil && time.Since(info.ModTime()) > maxIdle {
+			os.Remove(file)
+		}
+	}
+	return nil
+}
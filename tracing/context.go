@@ -0,0 +1,149 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+type contextKey string
+
+const (
+	contextSpan = contextKey("tracing.span")
+
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+type TraceID [16]byte
+
+type SpanID [8]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+}
+
+func (sc SpanContext) Sampled() bool {
+	return sc.Flags&0x01 == 0x01
+}
+
+func (sc SpanContext) TraceParent() string {
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{sc.Flags})
+}
+
+// Child keeps trace id, flags and state, but uses a fresh span id
+func (sc SpanContext) Child() SpanContext {
+	sc.SpanID = newSpanID()
+	return sc
+}
+
+func NewRootSpanContext() SpanContext {
+	var traceID TraceID
+	for !traceID.IsValid() {
+		if _, err := rand.Read(traceID[:]); err != nil {
+			panic(err)
+		}
+	}
+	return SpanContext{TraceID: traceID, SpanID: newSpanID(), Flags: 0x01}
+}
+
+func newSpanID() (spanID SpanID) {
+	for !spanID.IsValid() {
+		if _, err := rand.Read(spanID[:]); err != nil {
+			panic(err)
+		}
+	}
+	return spanID
+}
+
+func ParseTraceParent(value string) (sc SpanContext, ok bool) {
+	if len(value) < 55 || (len(value) > 55 && value[55] != '-') {
+		return sc, false
+	}
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return sc, false
+	}
+	var version [1]byte
+	var flags [1]byte
+	if !decodeLowerHex(version[:], value[0:2]) || version[0] == 0xff {
+		return sc, false
+	}
+	if version[0] == 0x00 && len(value) != 55 {
+		return sc, false
+	}
+	if !decodeLowerHex(sc.TraceID[:], value[3:35]) || !sc.TraceID.IsValid() {
+		return sc, false
+	}
+	if !decodeLowerHex(sc.SpanID[:], value[36:52]) || !sc.SpanID.IsValid() {
+		return sc, false
+	}
+	if !decodeLowerHex(flags[:], value[53:55]) {
+		return sc, false
+	}
+	sc.Flags = flags[0]
+	return sc, true
+}
+
+func decodeLowerHex(dst []byte, value string) bool {
+	if strings.ToLower(value) != value {
+		return false
+	}
+	n, err := hex.Decode(dst, []byte(value))
+	return err == nil && n == len(dst)
+}
+
+// ValidTraceState does a structural check of the tracestate list, invalid values are dropped, not forwarded
+func ValidTraceState(value string) bool {
+	if len(value) > 512 {
+		return false
+	}
+	members := strings.Split(value, ",")
+	if len(members) > 32 {
+		return false
+	}
+	for _, member := range members {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		key, val, found := strings.Cut(member, "=")
+		if !found || key == "" || val == "" || len(key) > 256 || len(val) > 256 {
+			return false
+		}
+		for _, c := range val {
+			if c < 0x20 || c > 0x7e || c == ',' || c == '=' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func WithSpanContext(c context.Context, sc SpanContext) context.Context {
+	return context.WithValue(c, contextSpan, sc)
+}
+
+func GetSpanContext(c context.Context) (SpanContext, bool) {
+	sc, ok := c.Value(contextSpan).(SpanContext)
+	return sc, ok
+}
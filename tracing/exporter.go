@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+type Span struct {
+	Name         string
+	Context      SpanContext
+	ParentSpanID SpanID
+	Method       string
+	Path         string
+	Status       int
+	Start        time.Time
+	End          time.Time
+}
+
+type Exporter interface {
+	Export(span Span)
+}
+
+type MemoryExporter struct {
+	mutex sync.Mutex
+	spans []Span
+}
+
+func NewMemoryExporter() *MemoryExporter {
+	return &MemoryExporter{}
+}
+
+func (exporter *MemoryExporter) Export(span Span) {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	exporter.spans = append(exporter.spans, span)
+}
+
+func (exporter *MemoryExporter) Spans() []Span {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	return append([]Span{}, exporter.spans...)
+}
+
+type JSONExporter struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+func NewJSONExporter(writer io.Writer) *JSONExporter {
+	return &JSONExporter{encoder: json.NewEncoder(writer)}
+}
+
+func NewStdoutExporter() *JSONExporter {
+	return NewJSONExporter(os.Stdout)
+}
+
+type jsonSpan struct {
+	Name         string    `json:"name"`
+	TraceID      string    `json:"traceId"`
+	SpanID       string    `json:"spanId"`
+	ParentSpanID string    `json:"parentSpanId,omitempty"`
+	TraceState   string    `json:"traceState,omitempty"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	Status       int       `json:"status"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	DurationMs   float64   `json:"durationMs"`
+}
+
+func (exporter *JSONExporter) Export(span Span) {
+	value := jsonSpan{
+		Name:       span.Name,
+		TraceID:    span.Context.TraceID.String(),
+		SpanID:     span.Context.SpanID.String(),
+		TraceState: span.Context.TraceState,
+		Method:     span.Method,
+		Path:       span.Path,
+		Status:     span.Status,
+		Start:      span.Start,
+		End:        span.End,
+		DurationMs: float64(span.End.Sub(span.Start).Microseconds()) / 1000,
+	}
+	if span.ParentSpanID.IsValid() {
+		value.ParentSpanID = span.ParentSpanID.String()
+	}
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	exporter.encoder.Encode(value)
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mwildt/go-http/httputils"
+	"github.com/mwildt/go-http/routing"
+)
+
+type Tracer struct {
+	exporter Exporter
+}
+
+func New(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+func (tracer *Tracer) Filter(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	var parentSpanID SpanID
+	sc, ok := ParseTraceParent(r.Header.Get(TraceParentHeader))
+	if ok {
+		parentSpanID = sc.SpanID
+		if state := r.Header.Get(TraceStateHeader); ValidTraceState(state) {
+			sc.TraceState = state
+		}
+		sc = sc.Child()
+	} else {
+		sc = NewRootSpanContext()
+	}
+
+	// the request headers carry the current span, so handlers proxying this request propagate it downstream
+	Inject(r.Header, sc)
+
+	start := time.Now()
+	recorder := httputils.NewStatusRecorder(w)
+	next(recorder, r.WithContext(WithSpanContext(r.Context(), sc)))
+
+	tracer.exporter.Export(Span{
+		Name:         routing.GetRoutePattern(r.Context()),
+		Context:      sc,
+		ParentSpanID: parentSpanID,
+		Method:       r.Method,
+		Path:         r.URL.Path,
+		Status:       recorder.StatusCode(),
+		Start:        start,
+		End:          time.Now(),
+	})
+}
+
+func Inject(header http.Header, sc SpanContext) {
+	header.Set(TraceParentHeader, sc.TraceParent())
+	if sc.TraceState != "" {
+		header.Set(TraceStateHeader, sc.TraceState)
+	} else {
+		header.Del(TraceStateHeader)
+	}
+}
+
+type Transport struct {
+	Base http.RoundTripper
+}
+
+func (transport Transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	base := transport.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if sc, ok := GetSpanContext(request.Context()); ok {
+		request = request.Clone(request.Context())
+		Inject(request.Header, sc)
+	}
+	return base.RoundTrip(request)
+}
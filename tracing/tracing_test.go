@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	go_http "github.com/mwildt/go-http"
+	"github.com/mwildt/go-http/routing"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	sc, ok := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	go_http.Assert(t, ok, "valid traceparent rejected")
+	go_http.Assert(t, sc.TraceID.String() == "4bf92f3577b34da6a3ce929d0e0e4736", "unexpected trace id %s", sc.TraceID)
+	go_http.Assert(t, sc.SpanID.String() == "00f067aa0ba902b7", "unexpected span id %s", sc.SpanID)
+	go_http.Assert(t, sc.Sampled(), "unexpected flags %x", sc.Flags)
+
+	for _, invalid := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		_, ok := ParseTraceParent(invalid)
+		go_http.Assert(t, !ok, "invalid traceparent accepted '%s'", invalid)
+	}
+
+	_, ok = ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future")
+	go_http.Assert(t, ok, "future version rejected")
+}
+
+func TestFilterCreatesChildSpan(t *testing.T) {
+	exporter := NewMemoryExporter()
+	var downstream http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		downstream = request.Header.Clone()
+		writer.WriteHeader(http.StatusAccepted)
+	}))
+	defer backend.Close()
+	backendUrl, _ := url.Parse(backend.URL)
+
+	router := routing.NewRouter(func(router routing.Routing) {
+		router.Handle(routing.Path("/api/{id}").Filter(New(exporter).Filter), httputil.NewSingleHostReverseProxy(backendUrl))
+	})
+
+	req := httptest.NewRequest("GET", "http://example.com/api/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("tracestate", "vendor=value")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.Spans()
+	go_http.Assert(t, len(spans) == 1, "unexpected number of spans %d", len(spans))
+	span := spans[0]
+	go_http.Assert(t, span.Name == "/api/{id}", "unexpected span name %s", span.Name)
+	go_http.Assert(t, span.Status == http.StatusAccepted, "unexpected span status %d", span.Status)
+	go_http.Assert(t, span.Context.TraceID.String() == "4bf92f3577b34da6a3ce929d0e0e4736", "trace id not kept")
+	go_http.Assert(t, span.ParentSpanID.String() == "00f067aa0ba902b7", "unexpected parent %s", span.ParentSpanID)
+	go_http.Assert(t, span.Context.SpanID != span.ParentSpanID, "no child span id created")
+	go_http.Assert(t, downstream.Get("traceparent") == span.Context.TraceParent(), "unexpected downstream traceparent %s", downstream.Get("traceparent"))
+	go_http.Assert(t, downstream.Get("tracestate") == "vendor=value", "unexpected downstream tracestate %s", downstream.Get("tracestate"))
+}
+
+func TestFilterStartsNewTrace(t *testing.T) {
+	exporter := NewMemoryExporter()
+	var sc SpanContext
+	router := routing.NewRouter(func(router routing.Routing) {
+		router.HandleFunc(routing.Get("/test").Filter(New(exporter).Filter), func(writer http.ResponseWriter, request *http.Request) {
+			sc, _ = GetSpanContext(request.Context())
+		})
+	})
+	req := httptest.NewRequest("GET", "http://example.com/test", nil)
+	req.Header.Set("traceparent", "garbage")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.Spans()
+	go_http.Assert(t, len(spans) == 1, "unexpected number of spans %d", len(spans))
+	go_http.Assert(t, sc.TraceID.IsValid() && sc == spans[0].Context, "unexpected span context in request")
+	go_http.Assert(t, !spans[0].ParentSpanID.IsValid(), "root span must not have a parent")
+}
+
+func TestTransportInjectsSpan(t *testing.T) {
+	var received string
+	backend := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		received = request.Header.Get("traceparent")
+	}))
+	defer backend.Close()
+
+	sc := NewRootSpanContext()
+	req, _ := http.NewRequestWithContext(WithSpanContext(httptest.NewRequest("GET", "/", nil).Context(), sc), "GET", backend.URL, nil)
+	res, err := (&http.Client{Transport: Transport{}}).Do(req)
+	go_http.AssertNoError(t, err, "request failed")
+	res.Body.Close()
+	go_http.Assert(t, received == sc.TraceParent(), "unexpected traceparent %s", received)
+}
+
+func TestJSONExporter(t *testing.T) {
+	var out bytes.Buffer
+	sc := NewRootSpanContext()
+	NewJSONExporter(&out).Export(Span{Name: "/api/{id}", Context: sc, Status: 200})
+
+	var value map[string]any
+	go_http.AssertNoError(t, json.Unmarshal(out.Bytes(), &value), "invalid json")
+	go_http.Assert(t, value["name"] == "/api/{id}", "unexpected name %v", value["name"])
+	go_http.Assert(t, value["traceId"] == sc.TraceID.String(), "unexpected trace id %v", value["traceId"])
+	_, hasParent := value["parentSpanId"]
+	go_http.Assert(t, !hasParent, "unexpected parent span id")
+}